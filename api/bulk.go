@@ -0,0 +1,145 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedContentType is returned when a bulk upload is neither CSV nor NDJSON
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// bulkRow is a single parsed row of a bulk upload
+type bulkRow struct {
+	Line    int
+	Message NewMessage
+	Err     error
+}
+
+// parseBulkMessages parses a bulk upload according to its content type
+func parseBulkMessages(contentType string, body io.Reader) ([]bulkRow, error) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(mediaType) {
+	case "text/csv":
+		return parseCSVMessages(body)
+	case "application/x-ndjson":
+		return parseNDJSONMessages(body)
+	default:
+		return nil, ErrUnsupportedContentType
+	}
+}
+
+// parseCSVMessages parses CSV with a header row containing content and recipient columns
+func parseCSVMessages(body io.Reader) ([]bulkRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	contentIdx, recipientIdx, scheduledAtIdx, priorityIdx, timeZoneIdx := -1, -1, -1, -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "content":
+			contentIdx = i
+		case "recipient":
+			recipientIdx = i
+		case "scheduledat":
+			scheduledAtIdx = i
+		case "priority":
+			priorityIdx = i
+		case "timezone":
+			timeZoneIdx = i
+		}
+	}
+	if contentIdx == -1 || recipientIdx == -1 {
+		return nil, errors.New("CSV header must contain 'content' and 'recipient' columns")
+	}
+
+	var rows []bulkRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, bulkRow{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if contentIdx >= len(record) || recipientIdx >= len(record) {
+			rows = append(rows, bulkRow{Line: line, Err: errors.New("missing columns")})
+			continue
+		}
+
+		m := NewMessage{
+			Content:   record[contentIdx],
+			Recipient: strings.TrimSpace(record[recipientIdx]),
+		}
+		if scheduledAtIdx != -1 && scheduledAtIdx < len(record) && strings.TrimSpace(record[scheduledAtIdx]) != "" {
+			scheduledAt, err := time.Parse(time.RFC3339, strings.TrimSpace(record[scheduledAtIdx]))
+			if err != nil {
+				rows = append(rows, bulkRow{Line: line, Err: errors.New("scheduledAt must be an RFC 3339 date-time")})
+				continue
+			}
+			m.ScheduledAt = &scheduledAt
+		}
+		if priorityIdx != -1 && priorityIdx < len(record) && strings.TrimSpace(record[priorityIdx]) != "" {
+			priority, err := strconv.Atoi(strings.TrimSpace(record[priorityIdx]))
+			if err != nil {
+				rows = append(rows, bulkRow{Line: line, Err: errors.New("priority must be an integer")})
+				continue
+			}
+			m.Priority = &priority
+		}
+		if timeZoneIdx != -1 && timeZoneIdx < len(record) && strings.TrimSpace(record[timeZoneIdx]) != "" {
+			timeZone := strings.TrimSpace(record[timeZoneIdx])
+			m.TimeZone = &timeZone
+		}
+
+		rows = append(rows, bulkRow{Line: line, Message: m})
+	}
+
+	return rows, nil
+}
+
+// parseNDJSONMessages parses one JSON encoded message per line, skipping blank lines
+func parseNDJSONMessages(body io.Reader) ([]bulkRow, error) {
+	scanner := bufio.NewScanner(body)
+
+	var rows []bulkRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var m NewMessage
+		if err := json.Unmarshal([]byte(text), &m); err != nil {
+			rows = append(rows, bulkRow{Line: line, Err: errors.New("invalid JSON")})
+			continue
+		}
+		rows = append(rows, bulkRow{Line: line, Message: m})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrEmptyProviderMessageId = errors.New("messageId must not be empty")
+	ErrInvalidReceiptStatus   = errors.New("status must be delivered or undelivered")
+)
+
+// ValidateDeliveryReceipt checks that a delivery receipt can be recorded
+func ValidateDeliveryReceipt(receipt DeliveryReceipt) error {
+	if receipt.MessageId == "" {
+		return ErrEmptyProviderMessageId
+	}
+	if receipt.Status != Delivered && receipt.Status != Undelivered {
+		return ErrInvalidReceiptStatus
+	}
+	return nil
+}
+
+// ReceiveDeliveryReceipt records whether a message sent with the provider reached the handset
+func (s Server) ReceiveDeliveryReceipt(w http.ResponseWriter, r *http.Request, provider string) {
+	var body ReceiveDeliveryReceiptJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateDeliveryReceipt(body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if body.Status == Delivered && body.DeliveredAt == nil {
+		now := time.Now()
+		body.DeliveredAt = &now
+	}
+
+	err := s.DB.RecordDeliveryReceipt(provider, body)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "message not found", http.StatusNotFound)
+	case errors.Is(err, ErrConflict):
+		http.Error(w, "message is not sent", http.StatusConflict)
+	case err != nil:
+		http.Error(w, "failed to record delivery receipt", http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
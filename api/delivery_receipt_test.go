@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_ReceiveDeliveryReceipt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should record the receipt and return 204", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		deliveredAt := time.Date(2025, 5, 31, 10, 0, 5, 0, time.UTC)
+		mockDB.EXPECT().RecordDeliveryReceipt("somethirdparty", DeliveryReceipt{MessageId: "provider-1", Status: Delivered, DeliveredAt: &deliveredAt}).Return(nil)
+
+		r := httptest.NewRequest("POST", "/webhooks/delivery-receipts/somethirdparty", strings.NewReader(`{"messageId":"provider-1","status":"delivered","deliveredAt":"2025-05-31T10:00:05Z"}`))
+		w := httptest.NewRecorder()
+		s.ReceiveDeliveryReceipt(w, r, "somethirdparty")
+
+		require.Equal(tt, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("success - should default the delivery time to now", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().RecordDeliveryReceipt("somethirdparty", gomock.Any()).DoAndReturn(func(provider string, receipt DeliveryReceipt) error {
+			require.NotNil(tt, receipt.DeliveredAt)
+			require.WithinDuration(tt, time.Now(), *receipt.DeliveredAt, time.Second)
+			return nil
+		})
+
+		r := httptest.NewRequest("POST", "/webhooks/delivery-receipts/somethirdparty", strings.NewReader(`{"messageId":"provider-1","status":"delivered"}`))
+		w := httptest.NewRecorder()
+		s.ReceiveDeliveryReceipt(w, r, "somethirdparty")
+
+		require.Equal(tt, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("error - should return 400 for invalid receipts", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		bodies := []string{
+			`not json`,
+			`{"messageId":"","status":"delivered"}`,
+			`{"messageId":"provider-1","status":"sent"}`,
+			`{"messageId":"provider-1"}`,
+		}
+		for _, body := range bodies {
+			r := httptest.NewRequest("POST", "/webhooks/delivery-receipts/somethirdparty", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			s.ReceiveDeliveryReceipt(w, r, "somethirdparty")
+
+			require.Equal(tt, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("error - should map DB errors to status codes", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		expected := map[error]int{
+			ErrNotFound:               http.StatusNotFound,
+			ErrConflict:               http.StatusConflict,
+			fmt.Errorf("dummy error"): http.StatusInternalServerError,
+		}
+		for err, code := range expected {
+			mockDB.EXPECT().RecordDeliveryReceipt(gomock.Any(), gomock.Any()).Return(err)
+
+			r := httptest.NewRequest("POST", "/webhooks/delivery-receipts/somethirdparty", strings.NewReader(`{"messageId":"provider-1","status":"undelivered","error":"handset unreachable"}`))
+			w := httptest.NewRecorder()
+			s.ReceiveDeliveryReceipt(w, r, "somethirdparty")
+
+			require.Equal(tt, code, w.Code, err.Error())
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/taylankasap/message-sender/phonenumber"
+)
+
+// InboundMessageFilter narrows down and paginates the inbound messages returned by the database
+type InboundMessageFilter struct {
+	From    string // Only messages from this phone number, if not empty
+	AfterId int    // Only messages after the message with this id (the cursor), if not zero
+	Limit   int    // Maximum number of messages to return
+}
+
+// keywords maps the opt-out and opt-in keywords to what they mean. Besides the usual English
+// keywords, the common ones in Turkish, German, French, Spanish, Portuguese and Dutch are
+// recognized. Keywords are compared without diacritics, e.g. BAŞLA is matched as BASLA.
+var keywords = map[string]InboundKeyword{
+	"STOP":        Stop,
+	"STOPALL":     Stop,
+	"UNSUBSCRIBE": Stop,
+	"CANCEL":      Stop,
+	"END":         Stop,
+	"QUIT":        Stop,
+	"OPTOUT":      Stop,
+	"IPTAL":       Stop, // Turkish
+	"DUR":         Stop, // Turkish
+	"STOPP":       Stop, // German
+	"ABMELDEN":    Stop, // German
+	"ARRET":       Stop, // French
+	"BAJA":        Stop, // Spanish
+	"CANCELAR":    Stop, // Spanish and Portuguese
+	"PARAR":       Stop, // Spanish and Portuguese
+	"AFMELDEN":    Stop, // Dutch
+
+	"START":     Start,
+	"UNSTOP":    Start,
+	"SUBSCRIBE": Start,
+	"BASLA":     Start, // Turkish
+	"ANMELDEN":  Start, // German
+	"ALTA":      Start, // Spanish
+	"AANMELDEN": Start, // Dutch
+}
+
+// keywordFolder removes the diacritics used in the keywords and the Turkish dotless and dotted i,
+// which strings.ToUpper would not map to a plain I
+var keywordFolder = strings.NewReplacer(
+	"ı", "I", "İ", "I", "i̇", "I",
+	"ş", "S", "Ş", "S",
+	"ç", "C", "Ç", "C",
+	"ğ", "G", "Ğ", "G",
+	"ê", "E", "Ê", "E",
+)
+
+// detectKeyword returns the keyword the content consists of, or nil if it is not a keyword.
+// Only replies that contain nothing but the keyword count, so a reply like "please don't stop"
+// does not unsubscribe the sender. Case, surrounding punctuation and inner spaces (e.g. OPT OUT)
+// are ignored.
+func detectKeyword(content string) *InboundKeyword {
+	trimmed := strings.TrimFunc(content, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+	normalized := strings.ToUpper(keywordFolder.Replace(strings.Join(strings.Fields(trimmed), "")))
+
+	keyword, ok := keywords[normalized]
+	if !ok {
+		return nil
+	}
+	return &keyword
+}
+
+// ReceiveInboundMessage stores a message a recipient sent back through the provider and applies
+// the opt-out or opt-in keyword it contains
+func (s Server) ReceiveInboundMessage(w http.ResponseWriter, r *http.Request, provider string) {
+	var body ReceiveInboundMessageJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	normalizeRecipient(&body.From)
+	if !phonenumber.IsValid(body.From) {
+		http.Error(w, "from must be a phone number in E.164 format, e.g. +905551111111", http.StatusBadRequest)
+		return
+	}
+
+	if body.ReceivedAt == nil {
+		now := time.Now()
+		body.ReceivedAt = &now
+	}
+
+	msg, err := s.DB.InsertInboundMessage(provider, body, detectKeyword(body.Content))
+	if err != nil {
+		http.Error(w, "failed to store inbound message", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(msg)
+}
+
+// GetInboundMessages returns a page of the messages received from recipients
+func (s Server) GetInboundMessages(w http.ResponseWriter, r *http.Request, params GetInboundMessagesParams) {
+	limit, err := pageLimit(params.Limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	afterId, err := decodeCursor(params.After)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := InboundMessageFilter{
+		AfterId: afterId,
+		Limit:   limit + 1, // one more to know if there is a next page
+	}
+	if params.From != nil {
+		filter.From = *params.From
+		normalizeRecipient(&filter.From)
+	}
+
+	msgs, err := s.DB.GetInboundMessages(filter)
+	if err != nil {
+		http.Error(w, "failed to fetch inbound messages", http.StatusInternalServerError)
+		return
+	}
+
+	var resp InboundMessagesResponse
+	resp.Messages, resp.NextCursor = paginate(msgs, limit, inboundMessageId)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDetectKeyword(t *testing.T) {
+	t.Run("it should recognize the keywords regardless of case, punctuation and spaces", func(tt *testing.T) {
+		contents := map[string]InboundKeyword{
+			"STOP":          Stop,
+			"stop":          Stop,
+			" Stop! ":       Stop,
+			"Unsubscribe.":  Stop,
+			"opt out":       Stop,
+			"IPTAL":         Stop,
+			"iptal":         Stop,
+			"İptal":         Stop,
+			"İPTAL":         Stop,
+			"ıptal":         Stop,
+			"Arrêt":         Stop,
+			"START":         Start,
+			"start":         Start,
+			"Başla":         Start,
+			"BAŞLA":         Start,
+			"\"unstop\"\n":  Start,
+			"  subscribe  ": Start,
+		}
+		for content, expected := range contents {
+			keyword := detectKeyword(content)
+			require.NotNil(tt, keyword, content)
+			require.Equal(tt, expected, *keyword, content)
+		}
+	})
+
+	t.Run("it should ignore messages that are more than a keyword", func(tt *testing.T) {
+		for _, content := range []string{"", "Hello!", "please don't stop", "stop it", "STOPPING", "started"} {
+			require.Nil(tt, detectKeyword(content), content)
+		}
+	})
+}
+
+func TestServer_ReceiveInboundMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should store the message with the keyword it contains", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().InsertInboundMessage("somethirdparty", gomock.Any(), gomock.Any()).DoAndReturn(func(provider string, m NewInboundMessage, keyword *InboundKeyword) (InboundMessage, error) {
+			require.Equal(tt, "+905551111111", m.From)
+			require.Equal(tt, "İptal", m.Content)
+			require.NotNil(tt, m.ReceivedAt)
+			require.NotNil(tt, keyword)
+			require.Equal(tt, Stop, *keyword)
+			return InboundMessage{Id: 1, From: m.From, Content: m.Content, Keyword: keyword, ReceivedAt: *m.ReceivedAt}, nil
+		})
+
+		r := httptest.NewRequest("POST", "/webhooks/inbound-messages/somethirdparty", strings.NewReader(`{"from":"+90 555 111 11 11","content":"İptal"}`))
+		w := httptest.NewRecorder()
+		s.ReceiveInboundMessage(w, r, "somethirdparty")
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+
+		var actual InboundMessage
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actual))
+		require.Equal(tt, Stop, *actual.Keyword)
+	})
+
+	t.Run("success - should store messages without a keyword", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		receivedAt := time.Date(2025, 5, 31, 10, 0, 0, 0, time.UTC)
+		messageId := "dummy-message-id"
+		expected := NewInboundMessage{MessageId: &messageId, From: "+905551111111", Content: "Thanks!", ReceivedAt: &receivedAt}
+		mockDB.EXPECT().InsertInboundMessage("somethirdparty", expected, nil).Return(InboundMessage{Id: 1}, nil)
+
+		r := httptest.NewRequest("POST", "/webhooks/inbound-messages/somethirdparty", strings.NewReader(`{"messageId":"dummy-message-id","from":"+905551111111","content":"Thanks!","receivedAt":"2025-05-31T10:00:00Z"}`))
+		w := httptest.NewRecorder()
+		s.ReceiveInboundMessage(w, r, "somethirdparty")
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+	})
+
+	t.Run("error - should return 400 for invalid messages", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		for _, body := range []string{`{`, `{"content":"STOP"}`, `{"from":"5551111111","content":"STOP"}`} {
+			r := httptest.NewRequest("POST", "/webhooks/inbound-messages/somethirdparty", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			s.ReceiveInboundMessage(w, r, "somethirdparty")
+
+			require.Equal(tt, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("error - should return 500 on DB error", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().InsertInboundMessage(gomock.Any(), gomock.Any(), gomock.Any()).Return(InboundMessage{}, fmt.Errorf("dummy error"))
+
+		r := httptest.NewRequest("POST", "/webhooks/inbound-messages/somethirdparty", strings.NewReader(`{"from":"+905551111111","content":"STOP"}`))
+		w := httptest.NewRecorder()
+		s.ReceiveInboundMessage(w, r, "somethirdparty")
+
+		require.Equal(tt, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_GetInboundMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should return a page of messages and the cursor of the next one", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetInboundMessages(InboundMessageFilter{From: "+905551111111", Limit: 3}).Return([]InboundMessage{{Id: 1}, {Id: 2}, {Id: 3}}, nil)
+
+		limit := 2
+		from := "+90 555 111 11 11"
+		r := httptest.NewRequest("GET", "/inbound-messages", nil)
+		w := httptest.NewRecorder()
+		s.GetInboundMessages(w, r, GetInboundMessagesParams{Limit: &limit, From: &from})
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var resp InboundMessagesResponse
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+		require.Equal(tt, []InboundMessage{{Id: 1}, {Id: 2}}, resp.Messages)
+		require.NotNil(tt, resp.NextCursor)
+		require.Equal(tt, encodeCursor(2), *resp.NextCursor)
+	})
+
+	t.Run("success - should not return a cursor on the last page", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		after := encodeCursor(2)
+		mockDB.EXPECT().GetInboundMessages(InboundMessageFilter{AfterId: 2, Limit: DefaultPageLimit + 1}).Return([]InboundMessage{{Id: 3}}, nil)
+
+		r := httptest.NewRequest("GET", "/inbound-messages", nil)
+		w := httptest.NewRecorder()
+		s.GetInboundMessages(w, r, GetInboundMessagesParams{After: &after})
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var resp InboundMessagesResponse
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+		require.Len(tt, resp.Messages, 1)
+		require.Nil(tt, resp.NextCursor)
+	})
+
+	t.Run("error - should return 400 for invalid parameters", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		limit := 0
+		after := "!"
+		for _, params := range []GetInboundMessagesParams{{Limit: &limit}, {After: &after}} {
+			r := httptest.NewRequest("GET", "/inbound-messages", nil)
+			w := httptest.NewRecorder()
+			s.GetInboundMessages(w, r, params)
+
+			require.Equal(tt, http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("error - should return 500 on DB error", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetInboundMessages(gomock.Any()).Return(nil, fmt.Errorf("dummy error"))
+
+		r := httptest.NewRequest("GET", "/inbound-messages", nil)
+		w := httptest.NewRecorder()
+		s.GetInboundMessages(w, r, GetInboundMessagesParams{})
+
+		require.Equal(tt, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -39,17 +39,299 @@ func (m *MockDBInterface) EXPECT() *MockDBInterfaceMockRecorder {
 	return m.recorder
 }
 
+// CancelMessage mocks base method.
+func (m *MockDBInterface) CancelMessage(id int) (Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelMessage", id)
+	ret0, _ := ret[0].(Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelMessage indicates an expected call of CancelMessage.
+func (mr *MockDBInterfaceMockRecorder) CancelMessage(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelMessage", reflect.TypeOf((*MockDBInterface)(nil).CancelMessage), id)
+}
+
+// DeleteSuppression mocks base method.
+func (m *MockDBInterface) DeleteSuppression(recipient string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSuppression", recipient)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSuppression indicates an expected call of DeleteSuppression.
+func (mr *MockDBInterfaceMockRecorder) DeleteSuppression(recipient any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSuppression", reflect.TypeOf((*MockDBInterface)(nil).DeleteSuppression), recipient)
+}
+
+// DeleteTemplate mocks base method.
+func (m *MockDBInterface) DeleteTemplate(id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTemplate", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTemplate indicates an expected call of DeleteTemplate.
+func (mr *MockDBInterfaceMockRecorder) DeleteTemplate(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTemplate", reflect.TypeOf((*MockDBInterface)(nil).DeleteTemplate), id)
+}
+
+// GetInboundMessages mocks base method.
+func (m *MockDBInterface) GetInboundMessages(filter InboundMessageFilter) ([]InboundMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInboundMessages", filter)
+	ret0, _ := ret[0].([]InboundMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInboundMessages indicates an expected call of GetInboundMessages.
+func (mr *MockDBInterfaceMockRecorder) GetInboundMessages(filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInboundMessages", reflect.TypeOf((*MockDBInterface)(nil).GetInboundMessages), filter)
+}
+
+// GetMessage mocks base method.
+func (m *MockDBInterface) GetMessage(id int) (Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessage", id)
+	ret0, _ := ret[0].(Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessage indicates an expected call of GetMessage.
+func (mr *MockDBInterfaceMockRecorder) GetMessage(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessage", reflect.TypeOf((*MockDBInterface)(nil).GetMessage), id)
+}
+
+// GetMessageByProviderMessageId mocks base method.
+func (m *MockDBInterface) GetMessageByProviderMessageId(provider, providerMessageId string) (Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessageByProviderMessageId", provider, providerMessageId)
+	ret0, _ := ret[0].(Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessageByProviderMessageId indicates an expected call of GetMessageByProviderMessageId.
+func (mr *MockDBInterfaceMockRecorder) GetMessageByProviderMessageId(provider, providerMessageId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageByProviderMessageId", reflect.TypeOf((*MockDBInterface)(nil).GetMessageByProviderMessageId), provider, providerMessageId)
+}
+
+// GetMessages mocks base method.
+func (m *MockDBInterface) GetMessages(filter MessageFilter) ([]Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessages", filter)
+	ret0, _ := ret[0].([]Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessages indicates an expected call of GetMessages.
+func (mr *MockDBInterfaceMockRecorder) GetMessages(filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessages", reflect.TypeOf((*MockDBInterface)(nil).GetMessages), filter)
+}
+
 // GetSentMessages mocks base method.
-func (m *MockDBInterface) GetSentMessages() ([]Message, error) {
+func (m *MockDBInterface) GetSentMessages(filter MessageFilter) ([]Message, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSentMessages")
+	ret := m.ctrl.Call(m, "GetSentMessages", filter)
 	ret0, _ := ret[0].([]Message)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetSentMessages indicates an expected call of GetSentMessages.
-func (mr *MockDBInterfaceMockRecorder) GetSentMessages() *gomock.Call {
+func (mr *MockDBInterfaceMockRecorder) GetSentMessages(filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSentMessages", reflect.TypeOf((*MockDBInterface)(nil).GetSentMessages), filter)
+}
+
+// GetSuppression mocks base method.
+func (m *MockDBInterface) GetSuppression(recipient string) (Suppression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSuppression", recipient)
+	ret0, _ := ret[0].(Suppression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSuppression indicates an expected call of GetSuppression.
+func (mr *MockDBInterfaceMockRecorder) GetSuppression(recipient any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSuppression", reflect.TypeOf((*MockDBInterface)(nil).GetSuppression), recipient)
+}
+
+// GetSuppressions mocks base method.
+func (m *MockDBInterface) GetSuppressions() ([]Suppression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSuppressions")
+	ret0, _ := ret[0].([]Suppression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSuppressions indicates an expected call of GetSuppressions.
+func (mr *MockDBInterfaceMockRecorder) GetSuppressions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSuppressions", reflect.TypeOf((*MockDBInterface)(nil).GetSuppressions))
+}
+
+// GetTemplate mocks base method.
+func (m *MockDBInterface) GetTemplate(id int) (Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTemplate", id)
+	ret0, _ := ret[0].(Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTemplate indicates an expected call of GetTemplate.
+func (mr *MockDBInterfaceMockRecorder) GetTemplate(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTemplate", reflect.TypeOf((*MockDBInterface)(nil).GetTemplate), id)
+}
+
+// GetTemplates mocks base method.
+func (m *MockDBInterface) GetTemplates() ([]Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTemplates")
+	ret0, _ := ret[0].([]Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTemplates indicates an expected call of GetTemplates.
+func (mr *MockDBInterfaceMockRecorder) GetTemplates() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTemplates", reflect.TypeOf((*MockDBInterface)(nil).GetTemplates))
+}
+
+// InsertInboundMessage mocks base method.
+func (m_2 *MockDBInterface) InsertInboundMessage(provider string, m NewInboundMessage, keyword *InboundKeyword) (InboundMessage, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "InsertInboundMessage", provider, m, keyword)
+	ret0, _ := ret[0].(InboundMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertInboundMessage indicates an expected call of InsertInboundMessage.
+func (mr *MockDBInterfaceMockRecorder) InsertInboundMessage(provider, m, keyword any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertInboundMessage", reflect.TypeOf((*MockDBInterface)(nil).InsertInboundMessage), provider, m, keyword)
+}
+
+// InsertMessage mocks base method.
+func (m_2 *MockDBInterface) InsertMessage(m NewMessage, idempotencyKey string) (Message, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "InsertMessage", m, idempotencyKey)
+	ret0, _ := ret[0].(Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertMessage indicates an expected call of InsertMessage.
+func (mr *MockDBInterfaceMockRecorder) InsertMessage(m, idempotencyKey any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertMessage", reflect.TypeOf((*MockDBInterface)(nil).InsertMessage), m, idempotencyKey)
+}
+
+// InsertMessages mocks base method.
+func (m *MockDBInterface) InsertMessages(msgs []NewMessage, idempotencyKeys []string) ([]Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertMessages", msgs, idempotencyKeys)
+	ret0, _ := ret[0].([]Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertMessages indicates an expected call of InsertMessages.
+func (mr *MockDBInterfaceMockRecorder) InsertMessages(msgs, idempotencyKeys any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertMessages", reflect.TypeOf((*MockDBInterface)(nil).InsertMessages), msgs, idempotencyKeys)
+}
+
+// InsertSuppression mocks base method.
+func (m *MockDBInterface) InsertSuppression(s NewSuppression) (Suppression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertSuppression", s)
+	ret0, _ := ret[0].(Suppression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertSuppression indicates an expected call of InsertSuppression.
+func (mr *MockDBInterfaceMockRecorder) InsertSuppression(s any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertSuppression", reflect.TypeOf((*MockDBInterface)(nil).InsertSuppression), s)
+}
+
+// InsertTemplate mocks base method.
+func (m *MockDBInterface) InsertTemplate(t NewTemplate) (Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertTemplate", t)
+	ret0, _ := ret[0].(Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertTemplate indicates an expected call of InsertTemplate.
+func (mr *MockDBInterfaceMockRecorder) InsertTemplate(t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertTemplate", reflect.TypeOf((*MockDBInterface)(nil).InsertTemplate), t)
+}
+
+// RecordDeliveryReceipt mocks base method.
+func (m *MockDBInterface) RecordDeliveryReceipt(provider string, receipt DeliveryReceipt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordDeliveryReceipt", provider, receipt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordDeliveryReceipt indicates an expected call of RecordDeliveryReceipt.
+func (mr *MockDBInterfaceMockRecorder) RecordDeliveryReceipt(provider, receipt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordDeliveryReceipt", reflect.TypeOf((*MockDBInterface)(nil).RecordDeliveryReceipt), provider, receipt)
+}
+
+// UpdateMessage mocks base method.
+func (m *MockDBInterface) UpdateMessage(id int, update MessageUpdate) (Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMessage", id, update)
+	ret0, _ := ret[0].(Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateMessage indicates an expected call of UpdateMessage.
+func (mr *MockDBInterfaceMockRecorder) UpdateMessage(id, update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMessage", reflect.TypeOf((*MockDBInterface)(nil).UpdateMessage), id, update)
+}
+
+// UpdateTemplate mocks base method.
+func (m *MockDBInterface) UpdateTemplate(id int, t NewTemplate) (Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTemplate", id, t)
+	ret0, _ := ret[0].(Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTemplate indicates an expected call of UpdateTemplate.
+func (mr *MockDBInterfaceMockRecorder) UpdateTemplate(id, t any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSentMessages", reflect.TypeOf((*MockDBInterface)(nil).GetSentMessages))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTemplate", reflect.TypeOf((*MockDBInterface)(nil).UpdateTemplate), id, t)
 }
@@ -0,0 +1,68 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/taylankasap/message-sender/api (interfaces: Router)
+//
+// Generated by this command:
+//
+//	mockgen --package=api --destination=mock_router.go . Router
+//
+
+// Package api is a generated GoMock package.
+package api
+
+import (
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRouter is a mock of Router interface.
+type MockRouter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRouterMockRecorder
+	isgomock struct{}
+}
+
+// MockRouterMockRecorder is the mock recorder for MockRouter.
+type MockRouterMockRecorder struct {
+	mock *MockRouter
+}
+
+// NewMockRouter creates a new mock instance.
+func NewMockRouter(ctrl *gomock.Controller) *MockRouter {
+	mock := &MockRouter{ctrl: ctrl}
+	mock.recorder = &MockRouterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRouter) EXPECT() *MockRouterMockRecorder {
+	return m.recorder
+}
+
+// Routes mocks base method.
+func (m *MockRouter) Routes() RoutingTable {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Routes")
+	ret0, _ := ret[0].(RoutingTable)
+	return ret0
+}
+
+// Routes indicates an expected call of Routes.
+func (mr *MockRouterMockRecorder) Routes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Routes", reflect.TypeOf((*MockRouter)(nil).Routes))
+}
+
+// SetRoutes mocks base method.
+func (m *MockRouter) SetRoutes(table RoutingTable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRoutes", table)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRoutes indicates an expected call of SetRoutes.
+func (mr *MockRouterMockRecorder) SetRoutes(table any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRoutes", reflect.TypeOf((*MockRouter)(nil).SetRoutes), table)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 1000
+)
+
+var (
+	ErrInvalidLimit  = errors.New("limit must be between 1 and 1000")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidStatus = errors.New("invalid status")
+	ErrInvalidOrder  = errors.New("order must be asc or desc")
+)
+
+// MessageFilter narrows down and paginates the messages returned by the database
+type MessageFilter struct {
+	Statuses   []MessageStatus // Only messages in one of these statuses, if not empty
+	Recipient  string          // Only messages to this recipient, if not empty
+	SentFrom   *time.Time      // Only messages sent at or after this time, if set
+	SentTo     *time.Time      // Only messages sent before this time, if set
+	AfterId    int             // Only messages after the message with this id (the cursor), if not zero
+	Descending bool            // Newest messages first instead of oldest
+	Limit      int             // Maximum number of messages to return
+}
+
+// pageLimit returns the requested page size or the default one
+func pageLimit(limit *int) (int, error) {
+	if limit == nil {
+		return DefaultPageLimit, nil
+	}
+	if *limit < 1 || *limit > MaxPageLimit {
+		return 0, ErrInvalidLimit
+	}
+	return *limit, nil
+}
+
+// encodeCursor returns an opaque cursor pointing after the message with the given id
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+// decodeCursor returns the message id the cursor points after, or zero if there is no cursor
+func decodeCursor(cursor *string) (int, error) {
+	if cursor == nil || *cursor == "" {
+		return 0, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(*cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.Atoi(string(decoded))
+	if err != nil || id < 1 {
+		return 0, ErrInvalidCursor
+	}
+
+	return id, nil
+}
+
+// paginate trims a result fetched with one extra row to the limit and returns the
+// cursor of the next page, made from the id of the last item, or nil if this is the last page
+func paginate[T any](items []T, limit int, id func(T) int) ([]T, *string) {
+	if len(items) <= limit {
+		return items, nil
+	}
+
+	items = items[:limit]
+	next := encodeCursor(id(items[len(items)-1]))
+	return items, &next
+}
+
+// messageId and inboundMessageId return the id the cursor of a page is made from
+func messageId(m Message) int               { return m.Id }
+func inboundMessageId(m InboundMessage) int { return m.Id }
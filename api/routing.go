@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+)
+
+var (
+	ErrInvalidRoutePrefix   = errors.New("route prefix must be a '+' followed by a country code, e.g. +90")
+	ErrDuplicateRoutePrefix = errors.New("route prefixes must be unique")
+	ErrEmptyRouteProviders  = errors.New("every route must have at least one provider")
+
+	// ErrUnknownProvider is returned by Router when a route refers to a provider that does not exist
+	ErrUnknownProvider = errors.New("unknown provider")
+)
+
+var routePrefixPattern = regexp.MustCompile(`^\+[1-9][0-9]{0,14}$`)
+
+//go:generate go tool mockgen --package=api --destination=mock_router.go . Router
+type Router interface {
+	Routes() RoutingTable
+	SetRoutes(table RoutingTable) error
+}
+
+// ValidateRoutingTable checks that every route of the table can be matched against a recipient
+func ValidateRoutingTable(table RoutingTable) error {
+	prefixes := map[string]bool{}
+	for _, route := range table.Routes {
+		if !routePrefixPattern.MatchString(route.Prefix) {
+			return ErrInvalidRoutePrefix
+		}
+		if prefixes[route.Prefix] {
+			return ErrDuplicateRoutePrefix
+		}
+		prefixes[route.Prefix] = true
+
+		if len(route.Providers) == 0 {
+			return ErrEmptyRouteProviders
+		}
+	}
+	return nil
+}
+
+// GetRoutes returns the routing table
+func (s Server) GetRoutes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.Router.Routes())
+}
+
+// UpdateRoutes validates and replaces the routing table
+func (s Server) UpdateRoutes(w http.ResponseWriter, r *http.Request) {
+	var body UpdateRoutesJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateRoutingTable(body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := s.Router.SetRoutes(body)
+	if errors.Is(err, ErrUnknownProvider) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to update routes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.Router.Routes())
+}
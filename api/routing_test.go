@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_GetRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRouter := NewMockRouter(ctrl)
+	s := Server{Router: mockRouter}
+
+	expected := RoutingTable{DefaultProviders: []string{"global"}, Routes: []Route{{Prefix: "+90", Providers: []string{"turkey"}}}}
+	mockRouter.EXPECT().Routes().Return(expected)
+
+	r := httptest.NewRequest("GET", "/routes", nil)
+	w := httptest.NewRecorder()
+	s.GetRoutes(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var actual RoutingTable
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+	require.Equal(t, expected, actual)
+}
+
+func TestServer_UpdateRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should replace the routes and return them", func(tt *testing.T) {
+		mockRouter := NewMockRouter(ctrl)
+		s := Server{Router: mockRouter}
+
+		table := RoutingTable{DefaultProviders: []string{"global"}, Routes: []Route{{Prefix: "+90", Providers: []string{"turkey", "global"}}}}
+		mockRouter.EXPECT().SetRoutes(table).Return(nil)
+		mockRouter.EXPECT().Routes().Return(table)
+
+		r := httptest.NewRequest("PUT", "/routes", strings.NewReader(`{"defaultProviders":["global"],"routes":[{"prefix":"+90","providers":["turkey","global"]}]}`))
+		w := httptest.NewRecorder()
+		s.UpdateRoutes(w, r)
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var actual RoutingTable
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actual))
+		require.Equal(tt, table, actual)
+	})
+
+	t.Run("error - should return 400 for invalid routes", func(tt *testing.T) {
+		mockRouter := NewMockRouter(ctrl)
+		s := Server{Router: mockRouter}
+
+		bodies := []string{
+			`not json`,
+			`{"routes":[{"prefix":"90","providers":["turkey"]}]}`,
+			`{"routes":[{"prefix":"+0","providers":["turkey"]}]}`,
+			`{"routes":[{"prefix":"+90","providers":[]}]}`,
+			`{"routes":[{"prefix":"+90","providers":["turkey"]},{"prefix":"+90","providers":["global"]}]}`,
+		}
+		for _, body := range bodies {
+			r := httptest.NewRequest("PUT", "/routes", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			s.UpdateRoutes(w, r)
+
+			require.Equal(tt, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("error - should return 400 if a provider does not exist", func(tt *testing.T) {
+		mockRouter := NewMockRouter(ctrl)
+		s := Server{Router: mockRouter}
+
+		mockRouter.EXPECT().SetRoutes(gomock.Any()).Return(fmt.Errorf("%w: usa", ErrUnknownProvider))
+
+		r := httptest.NewRequest("PUT", "/routes", strings.NewReader(`{"routes":[{"prefix":"+1","providers":["usa"]}]}`))
+		w := httptest.NewRecorder()
+		s.UpdateRoutes(w, r)
+
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+		require.Contains(tt, w.Body.String(), "unknown provider: usa")
+	})
+}
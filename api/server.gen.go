@@ -13,11 +13,23 @@ import (
 	"github.com/oapi-codegen/runtime"
 )
 
+// Defines values for InboundKeyword.
+const (
+	Start InboundKeyword = "start"
+	Stop  InboundKeyword = "stop"
+)
+
 // Defines values for MessageStatus.
 const (
-	Invalid MessageStatus = "invalid"
-	Sent    MessageStatus = "sent"
-	Unsent  MessageStatus = "unsent"
+	Cancelled   MessageStatus = "cancelled"
+	Delivered   MessageStatus = "delivered"
+	Failed      MessageStatus = "failed"
+	Invalid     MessageStatus = "invalid"
+	Sending     MessageStatus = "sending"
+	Sent        MessageStatus = "sent"
+	Suppressed  MessageStatus = "suppressed"
+	Undelivered MessageStatus = "undelivered"
+	Unsent      MessageStatus = "unsent"
 )
 
 // Defines values for ChangeStateParamsAction.
@@ -26,26 +38,261 @@ const (
 	Resume ChangeStateParamsAction = "resume"
 )
 
+// Defines values for GetMessagesParamsOrder.
+const (
+	Asc  GetMessagesParamsOrder = "asc"
+	Desc GetMessagesParamsOrder = "desc"
+)
+
+// BulkImportReport defines model for BulkImportReport.
+type BulkImportReport struct {
+	// Accepted Number of rows that were stored
+	Accepted int `json:"accepted"`
+
+	// Rejected Number of rows that were rejected
+	Rejected int             `json:"rejected"`
+	Rows     []BulkImportRow `json:"rows"`
+}
+
+// BulkImportRow defines model for BulkImportRow.
+type BulkImportRow struct {
+	// Error Reason the row was rejected
+	Error *string `json:"error,omitempty"`
+
+	// Line Line number of the row in the uploaded file
+	Line    int      `json:"line"`
+	Message *Message `json:"message,omitempty"`
+}
+
+// DeliveryReceipt Delivery status of a sent message, the status is either delivered or undelivered
+type DeliveryReceipt struct {
+	// DeliveredAt When the message reached the handset, defaults to the time the receipt is received
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+
+	// Error Why the message could not be delivered
+	Error *string `json:"error,omitempty"`
+
+	// MessageId Id the provider returned when it accepted the message
+	MessageId string        `json:"messageId"`
+	Status    MessageStatus `json:"status"`
+}
+
+// InboundKeyword Opt-out (stop) or opt-in (start) keyword recognized in an inbound message
+type InboundKeyword string
+
+// InboundMessage defines model for InboundMessage.
+type InboundMessage struct {
+	Content string `json:"content"`
+	From    string `json:"from"`
+	Id      int    `json:"id"`
+
+	// Keyword Opt-out (stop) or opt-in (start) keyword recognized in an inbound message
+	Keyword *InboundKeyword `json:"keyword,omitempty"`
+
+	// Provider Name of the provider that received the message
+	Provider *string `json:"provider,omitempty"`
+
+	// ProviderMessageId Id the provider assigned to the message
+	ProviderMessageId *string   `json:"providerMessageId,omitempty"`
+	ReceivedAt        time.Time `json:"receivedAt"`
+	To                *string   `json:"to,omitempty"`
+}
+
+// InboundMessagesResponse defines model for InboundMessagesResponse.
+type InboundMessagesResponse struct {
+	Messages []InboundMessage `json:"messages"`
+
+	// NextCursor Cursor of the next page, missing on the last page
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
 // Message defines model for Message.
 type Message struct {
-	Content   string        `json:"content"`
-	Id        int           `json:"id"`
-	Recipient string        `json:"recipient"`
-	SentAt    *time.Time    `json:"sentAt,omitempty"`
-	Status    MessageStatus `json:"status"`
+	// Attempts Number of failed attempts to send the message
+	Attempts int    `json:"attempts"`
+	Content  string `json:"content"`
+
+	// DeliveredAt When the message reached the handset according to the delivery receipt
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+	Id          int        `json:"id"`
+
+	// LastError Reason of the last failed attempt
+	LastError *string `json:"lastError,omitempty"`
+
+	// NextAttemptAt The message will not be retried before this time
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+
+	// Priority Messages with a higher priority are sent first
+	Priority int `json:"priority"`
+
+	// Provider Name of the provider that sent the message, or that an unsent message is retried with because its last attempt timed out
+	Provider *string `json:"provider,omitempty"`
+
+	// ProviderMessageId Id the provider returned when it accepted the message
+	ProviderMessageId *string `json:"providerMessageId,omitempty"`
+	Recipient         string  `json:"recipient"`
+
+	// ScheduledAt The message will not be sent before this time
+	ScheduledAt *time.Time `json:"scheduledAt,omitempty"`
+
+	// Segments Number of SMS parts the message is sent in, more than one if it is sent as a concatenated SMS
+	Segments int           `json:"segments"`
+	SentAt   *time.Time    `json:"sentAt,omitempty"`
+	Status   MessageStatus `json:"status"`
+
+	// TimeZone IANA time zone of the recipient, used for quiet hours instead of the time zone of the country code
+	TimeZone *string `json:"timeZone,omitempty"`
 }
 
-// MessageStatus defines model for Message.Status.
+// MessageStatus defines model for MessageStatus.
 type MessageStatus string
 
+// MessageUpdate defines model for MessageUpdate.
+type MessageUpdate struct {
+	Content  *string `json:"content,omitempty"`
+	Priority *int    `json:"priority,omitempty"`
+
+	// Recipient Phone number in E.164 format, normalized like the recipient of a new message
+	Recipient   *string    `json:"recipient,omitempty"`
+	ScheduledAt *time.Time `json:"scheduledAt,omitempty"`
+
+	// TimeZone IANA time zone of the recipient
+	TimeZone *string `json:"timeZone,omitempty"`
+}
+
+// MessagesResponse defines model for MessagesResponse.
+type MessagesResponse struct {
+	Messages []Message `json:"messages"`
+
+	// NextCursor Cursor of the next page, missing on the last page
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+// NewInboundMessage defines model for NewInboundMessage.
+type NewInboundMessage struct {
+	Content string `json:"content"`
+
+	// From Phone number of the sender, normalized like the recipient of a new message
+	From string `json:"from"`
+
+	// MessageId Id the provider assigned to the message, used to ignore duplicates
+	MessageId *string `json:"messageId,omitempty"`
+
+	// ReceivedAt When the provider received the message, defaults to the time it is received here
+	ReceivedAt *time.Time `json:"receivedAt,omitempty"`
+
+	// To Number the message was sent to
+	To *string `json:"to,omitempty"`
+}
+
+// NewMessage defines model for NewMessage.
+type NewMessage struct {
+	// Content Content of the message, required unless a template is used. A single SMS holds 160 characters if every character is in the GSM-7 alphabet and 70 otherwise (e.g. with emojis or Turkish characters). Longer messages are rejected, unless the server allows concatenated SMS, in which case every part holds 153 GSM-7 or 67 UCS-2 characters.
+	Content string `json:"content,omitempty"`
+
+	// Priority Messages with a higher priority are sent first (e.g. one time passwords), messages with the same priority are sent oldest first
+	Priority *int `json:"priority,omitempty"`
+
+	// Recipient Phone number in E.164 format. Spaces, dashes, dots and parentheses are removed and a leading 00 is replaced by '+', e.g. '+1 (418) 123-4567' is stored as '+14181234567'
+	Recipient string `json:"recipient"`
+
+	// ScheduledAt Send the message at this time instead of as soon as possible
+	ScheduledAt *time.Time `json:"scheduledAt,omitempty"`
+
+	// TemplateId Id of the template to render the content from
+	TemplateId *int `json:"templateId,omitempty"`
+
+	// TimeZone IANA time zone of the recipient, e.g. 'America/New_York'. Messages are not sent during the quiet hours of the recipient, which are in the time zone of the country code of the recipient unless this is set.
+	TimeZone *string `json:"timeZone,omitempty"`
+
+	// Variables Values for the variables used in the template
+	Variables *map[string]string `json:"variables,omitempty"`
+}
+
+// NewSuppression defines model for NewSuppression.
+type NewSuppression struct {
+	// Reason Why the recipient opted out
+	Reason *string `json:"reason,omitempty"`
+
+	// Recipient Phone number, normalized like the recipient of a new message
+	Recipient string `json:"recipient"`
+}
+
+// NewTemplate defines model for NewTemplate.
+type NewTemplate struct {
+	Body string `json:"body"`
+	Name string `json:"name"`
+}
+
+// Route defines model for Route.
+type Route struct {
+	// Prefix Recipients starting with this prefix, the longest matching prefix wins
+	Prefix string `json:"prefix"`
+
+	// Providers Providers to send the message with, in the order they are tried
+	Providers []string `json:"providers"`
+}
+
+// RoutingTable defines model for RoutingTable.
+type RoutingTable struct {
+	// DefaultProviders Providers for recipients that do not match any route, in the order they are tried. Every provider is used, in the configured order, if this is empty.
+	DefaultProviders []string `json:"defaultProviders"`
+	Routes           []Route  `json:"routes"`
+}
+
 // SentMessagesResponse defines model for SentMessagesResponse.
-type SentMessagesResponse = []Message
+type SentMessagesResponse struct {
+	Messages []Message `json:"messages"`
+
+	// NextCursor Cursor of the next page, missing on the last page
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
 
 // State defines model for State.
 type State struct {
 	Running bool `json:"running"`
 }
 
+// Suppression defines model for Suppression.
+type Suppression struct {
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Reason Why the recipient opted out
+	Reason    *string `json:"reason,omitempty"`
+	Recipient string  `json:"recipient"`
+}
+
+// SuppressionsResponse defines model for SuppressionsResponse.
+type SuppressionsResponse = []Suppression
+
+// Template defines model for Template.
+type Template struct {
+	Body string `json:"body"`
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// TemplatesResponse defines model for TemplatesResponse.
+type TemplatesResponse = []Template
+
+// After defines model for After.
+type After = string
+
+// IdempotencyKey defines model for IdempotencyKey.
+type IdempotencyKey = string
+
+// Limit defines model for Limit.
+type Limit = int
+
+// MessageId defines model for MessageId.
+type MessageId = int
+
+// Recipient defines model for Recipient.
+type Recipient = string
+
+// TemplateId defines model for TemplateId.
+type TemplateId = int
+
 // ChangeStateParams defines parameters for ChangeState.
 type ChangeStateParams struct {
 	// Action Action to perform on the message sender
@@ -55,14 +302,170 @@ type ChangeStateParams struct {
 // ChangeStateParamsAction defines parameters for ChangeState.
 type ChangeStateParamsAction string
 
+// GetInboundMessagesParams defines parameters for GetInboundMessages.
+type GetInboundMessagesParams struct {
+	// Limit Maximum number of messages to return
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// After Cursor returned as `nextCursor` by the previous page
+	After *After `form:"after,omitempty" json:"after,omitempty"`
+
+	// From Only return messages from this phone number
+	From *string `form:"from,omitempty" json:"from,omitempty"`
+}
+
+// GetMessagesParams defines parameters for GetMessages.
+type GetMessagesParams struct {
+	// Limit Maximum number of messages to return
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// After Cursor returned as `nextCursor` by the previous page
+	After *After `form:"after,omitempty" json:"after,omitempty"`
+
+	// Status Only return messages in one of these statuses
+	Status *[]MessageStatus `form:"status,omitempty" json:"status,omitempty"`
+
+	// Recipient Only return messages to this recipient
+	Recipient *string `form:"recipient,omitempty" json:"recipient,omitempty"`
+
+	// Order Return the oldest (asc) or the newest (desc) messages first
+	Order *GetMessagesParamsOrder `form:"order,omitempty" json:"order,omitempty"`
+}
+
+// GetMessagesParamsOrder defines parameters for GetMessages.
+type GetMessagesParamsOrder string
+
+// CreateMessageParams defines parameters for CreateMessage.
+type CreateMessageParams struct {
+	// IdempotencyKey Unique key chosen by the client to make retries safe. Repeating a request with the same key returns the originally created message instead of creating a duplicate. For bulk uploads the key applies to the whole file and rows are matched by line number. Reusing a key with a different body is rejected with 422.
+	IdempotencyKey *IdempotencyKey `json:"Idempotency-Key,omitempty"`
+}
+
+// CreateMessagesBulkParams defines parameters for CreateMessagesBulk.
+type CreateMessagesBulkParams struct {
+	// IdempotencyKey Unique key chosen by the client to make retries safe. Repeating a request with the same key returns the originally created message instead of creating a duplicate. For bulk uploads the key applies to the whole file and rows are matched by line number. Reusing a key with a different body is rejected with 422.
+	IdempotencyKey *IdempotencyKey `json:"Idempotency-Key,omitempty"`
+}
+
+// GetMessageByProviderMessageIdParams defines parameters for GetMessageByProviderMessageId.
+type GetMessageByProviderMessageIdParams struct {
+	// Provider Name of the provider that accepted the message, as in the config
+	Provider *string `form:"provider,omitempty" json:"provider,omitempty"`
+}
+
+// GetSentMessagesParams defines parameters for GetSentMessages.
+type GetSentMessagesParams struct {
+	// Limit Maximum number of messages to return
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// After Cursor returned as `nextCursor` by the previous page
+	After *After `form:"after,omitempty" json:"after,omitempty"`
+
+	// Recipient Only return messages sent to this recipient
+	Recipient *string `form:"recipient,omitempty" json:"recipient,omitempty"`
+
+	// SentFrom Only return messages sent at or after this time
+	SentFrom *time.Time `form:"sentFrom,omitempty" json:"sentFrom,omitempty"`
+
+	// SentTo Only return messages sent before this time
+	SentTo *time.Time `form:"sentTo,omitempty" json:"sentTo,omitempty"`
+}
+
+// CreateMessageJSONRequestBody defines body for CreateMessage for application/json ContentType.
+type CreateMessageJSONRequestBody = NewMessage
+
+// UpdateMessageJSONRequestBody defines body for UpdateMessage for application/json ContentType.
+type UpdateMessageJSONRequestBody = MessageUpdate
+
+// UpdateRoutesJSONRequestBody defines body for UpdateRoutes for application/json ContentType.
+type UpdateRoutesJSONRequestBody = RoutingTable
+
+// CreateSuppressionJSONRequestBody defines body for CreateSuppression for application/json ContentType.
+type CreateSuppressionJSONRequestBody = NewSuppression
+
+// CreateTemplateJSONRequestBody defines body for CreateTemplate for application/json ContentType.
+type CreateTemplateJSONRequestBody = NewTemplate
+
+// UpdateTemplateJSONRequestBody defines body for UpdateTemplate for application/json ContentType.
+type UpdateTemplateJSONRequestBody = NewTemplate
+
+// ReceiveDeliveryReceiptJSONRequestBody defines body for ReceiveDeliveryReceipt for application/json ContentType.
+type ReceiveDeliveryReceiptJSONRequestBody = DeliveryReceipt
+
+// ReceiveInboundMessageJSONRequestBody defines body for ReceiveInboundMessage for application/json ContentType.
+type ReceiveInboundMessageJSONRequestBody = NewInboundMessage
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
 	// Resume or pause the automatic message sender
 	// (GET /change-state)
 	ChangeState(w http.ResponseWriter, r *http.Request, params ChangeStateParams)
+	// Get inbound messages
+	// (GET /inbound-messages)
+	GetInboundMessages(w http.ResponseWriter, r *http.Request, params GetInboundMessagesParams)
+	// Get messages
+	// (GET /messages)
+	GetMessages(w http.ResponseWriter, r *http.Request, params GetMessagesParams)
+	// Create a message
+	// (POST /messages)
+	CreateMessage(w http.ResponseWriter, r *http.Request, params CreateMessageParams)
+	// Create messages in bulk
+	// (POST /messages/bulk)
+	CreateMessagesBulk(w http.ResponseWriter, r *http.Request, params CreateMessagesBulkParams)
+	// Get a message by its provider message id
+	// (GET /messages/by-provider-id/{providerMessageId})
+	GetMessageByProviderMessageId(w http.ResponseWriter, r *http.Request, providerMessageId string, params GetMessageByProviderMessageIdParams)
+	// Cancel an unsent message
+	// (DELETE /messages/{id})
+	CancelMessage(w http.ResponseWriter, r *http.Request, id MessageId)
+	// Get a message
+	// (GET /messages/{id})
+	GetMessage(w http.ResponseWriter, r *http.Request, id MessageId)
+	// Edit an unsent message
+	// (PATCH /messages/{id})
+	UpdateMessage(w http.ResponseWriter, r *http.Request, id MessageId)
+	// Get the provider routes
+	// (GET /routes)
+	GetRoutes(w http.ResponseWriter, r *http.Request)
+	// Replace the provider routes
+	// (PUT /routes)
+	UpdateRoutes(w http.ResponseWriter, r *http.Request)
 	// Get sent messages
 	// (GET /sent-messages)
-	GetSentMessages(w http.ResponseWriter, r *http.Request)
+	GetSentMessages(w http.ResponseWriter, r *http.Request, params GetSentMessagesParams)
+	// Get suppressed recipients
+	// (GET /suppressions)
+	GetSuppressions(w http.ResponseWriter, r *http.Request)
+	// Suppress a recipient
+	// (POST /suppressions)
+	CreateSuppression(w http.ResponseWriter, r *http.Request)
+	// Remove a recipient from the suppression list
+	// (DELETE /suppressions/{recipient})
+	DeleteSuppression(w http.ResponseWriter, r *http.Request, recipient Recipient)
+	// Get a suppressed recipient
+	// (GET /suppressions/{recipient})
+	GetSuppression(w http.ResponseWriter, r *http.Request, recipient Recipient)
+	// Get templates
+	// (GET /templates)
+	GetTemplates(w http.ResponseWriter, r *http.Request)
+	// Create a template
+	// (POST /templates)
+	CreateTemplate(w http.ResponseWriter, r *http.Request)
+	// Delete a template
+	// (DELETE /templates/{id})
+	DeleteTemplate(w http.ResponseWriter, r *http.Request, id TemplateId)
+	// Get a template
+	// (GET /templates/{id})
+	GetTemplate(w http.ResponseWriter, r *http.Request, id TemplateId)
+	// Replace a template
+	// (PUT /templates/{id})
+	UpdateTemplate(w http.ResponseWriter, r *http.Request, id TemplateId)
+	// Receive a delivery receipt
+	// (POST /webhooks/delivery-receipts/{provider})
+	ReceiveDeliveryReceipt(w http.ResponseWriter, r *http.Request, provider string)
+	// Receive an inbound message
+	// (POST /webhooks/inbound-messages/{provider})
+	ReceiveInboundMessage(w http.ResponseWriter, r *http.Request, provider string)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -108,11 +511,608 @@ func (siw *ServerInterfaceWrapper) ChangeState(w http.ResponseWriter, r *http.Re
 	handler.ServeHTTP(w, r)
 }
 
-// GetSentMessages operation middleware
-func (siw *ServerInterfaceWrapper) GetSentMessages(w http.ResponseWriter, r *http.Request) {
+// GetInboundMessages operation middleware
+func (siw *ServerInterfaceWrapper) GetInboundMessages(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetInboundMessagesParams
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "after", r.URL.Query(), &params.After)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "after", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetInboundMessages(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetMessages operation middleware
+func (siw *ServerInterfaceWrapper) GetMessages(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMessagesParams
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "after", r.URL.Query(), &params.After)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "after", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "status" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "status", r.URL.Query(), &params.Status)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "status", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "recipient" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "recipient", r.URL.Query(), &params.Recipient)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recipient", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "order" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "order", r.URL.Query(), &params.Order)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "order", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetMessages(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CreateMessage operation middleware
+func (siw *ServerInterfaceWrapper) CreateMessage(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CreateMessageParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "Idempotency-Key" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Idempotency-Key")]; found {
+		var IdempotencyKey IdempotencyKey
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "Idempotency-Key", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "Idempotency-Key", valueList[0], &IdempotencyKey, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "Idempotency-Key", Err: err})
+			return
+		}
+
+		params.IdempotencyKey = &IdempotencyKey
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateMessage(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CreateMessagesBulk operation middleware
+func (siw *ServerInterfaceWrapper) CreateMessagesBulk(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CreateMessagesBulkParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "Idempotency-Key" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Idempotency-Key")]; found {
+		var IdempotencyKey IdempotencyKey
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "Idempotency-Key", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "Idempotency-Key", valueList[0], &IdempotencyKey, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "Idempotency-Key", Err: err})
+			return
+		}
+
+		params.IdempotencyKey = &IdempotencyKey
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateMessagesBulk(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetMessageByProviderMessageId operation middleware
+func (siw *ServerInterfaceWrapper) GetMessageByProviderMessageId(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "providerMessageId" -------------
+	var providerMessageId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "providerMessageId", r.PathValue("providerMessageId"), &providerMessageId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "providerMessageId", Err: err})
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMessageByProviderMessageIdParams
+
+	// ------------- Optional query parameter "provider" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "provider", r.URL.Query(), &params.Provider)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetMessageByProviderMessageId(w, r, providerMessageId, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CancelMessage operation middleware
+func (siw *ServerInterfaceWrapper) CancelMessage(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id MessageId
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CancelMessage(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetMessage operation middleware
+func (siw *ServerInterfaceWrapper) GetMessage(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id MessageId
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetMessage(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// UpdateMessage operation middleware
+func (siw *ServerInterfaceWrapper) UpdateMessage(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id MessageId
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UpdateMessage(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetRoutes operation middleware
+func (siw *ServerInterfaceWrapper) GetRoutes(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetRoutes(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// UpdateRoutes operation middleware
+func (siw *ServerInterfaceWrapper) UpdateRoutes(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UpdateRoutes(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetSentMessages operation middleware
+func (siw *ServerInterfaceWrapper) GetSentMessages(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetSentMessagesParams
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "after", r.URL.Query(), &params.After)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "after", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "recipient" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "recipient", r.URL.Query(), &params.Recipient)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recipient", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "sentFrom" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sentFrom", r.URL.Query(), &params.SentFrom)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sentFrom", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "sentTo" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sentTo", r.URL.Query(), &params.SentTo)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sentTo", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetSentMessages(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetSuppressions operation middleware
+func (siw *ServerInterfaceWrapper) GetSuppressions(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetSuppressions(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CreateSuppression operation middleware
+func (siw *ServerInterfaceWrapper) CreateSuppression(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateSuppression(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteSuppression operation middleware
+func (siw *ServerInterfaceWrapper) DeleteSuppression(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "recipient" -------------
+	var recipient Recipient
+
+	err = runtime.BindStyledParameterWithOptions("simple", "recipient", r.PathValue("recipient"), &recipient, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recipient", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteSuppression(w, r, recipient)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetSuppression operation middleware
+func (siw *ServerInterfaceWrapper) GetSuppression(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "recipient" -------------
+	var recipient Recipient
+
+	err = runtime.BindStyledParameterWithOptions("simple", "recipient", r.PathValue("recipient"), &recipient, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recipient", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetSuppression(w, r, recipient)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetTemplates operation middleware
+func (siw *ServerInterfaceWrapper) GetTemplates(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetTemplates(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CreateTemplate operation middleware
+func (siw *ServerInterfaceWrapper) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateTemplate(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteTemplate operation middleware
+func (siw *ServerInterfaceWrapper) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id TemplateId
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteTemplate(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetTemplate operation middleware
+func (siw *ServerInterfaceWrapper) GetTemplate(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id TemplateId
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetTemplate(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// UpdateTemplate operation middleware
+func (siw *ServerInterfaceWrapper) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id TemplateId
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UpdateTemplate(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ReceiveDeliveryReceipt operation middleware
+func (siw *ServerInterfaceWrapper) ReceiveDeliveryReceipt(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "provider", r.PathValue("provider"), &provider, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ReceiveDeliveryReceipt(w, r, provider)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ReceiveInboundMessage operation middleware
+func (siw *ServerInterfaceWrapper) ReceiveInboundMessage(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "provider", r.PathValue("provider"), &provider, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetSentMessages(w, r)
+		siw.Handler.ReceiveInboundMessage(w, r, provider)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -243,7 +1243,28 @@ func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.H
 	}
 
 	m.HandleFunc("GET "+options.BaseURL+"/change-state", wrapper.ChangeState)
+	m.HandleFunc("GET "+options.BaseURL+"/inbound-messages", wrapper.GetInboundMessages)
+	m.HandleFunc("GET "+options.BaseURL+"/messages", wrapper.GetMessages)
+	m.HandleFunc("POST "+options.BaseURL+"/messages", wrapper.CreateMessage)
+	m.HandleFunc("POST "+options.BaseURL+"/messages/bulk", wrapper.CreateMessagesBulk)
+	m.HandleFunc("GET "+options.BaseURL+"/messages/by-provider-id/{providerMessageId}", wrapper.GetMessageByProviderMessageId)
+	m.HandleFunc("DELETE "+options.BaseURL+"/messages/{id}", wrapper.CancelMessage)
+	m.HandleFunc("GET "+options.BaseURL+"/messages/{id}", wrapper.GetMessage)
+	m.HandleFunc("PATCH "+options.BaseURL+"/messages/{id}", wrapper.UpdateMessage)
+	m.HandleFunc("GET "+options.BaseURL+"/routes", wrapper.GetRoutes)
+	m.HandleFunc("PUT "+options.BaseURL+"/routes", wrapper.UpdateRoutes)
 	m.HandleFunc("GET "+options.BaseURL+"/sent-messages", wrapper.GetSentMessages)
+	m.HandleFunc("GET "+options.BaseURL+"/suppressions", wrapper.GetSuppressions)
+	m.HandleFunc("POST "+options.BaseURL+"/suppressions", wrapper.CreateSuppression)
+	m.HandleFunc("DELETE "+options.BaseURL+"/suppressions/{recipient}", wrapper.DeleteSuppression)
+	m.HandleFunc("GET "+options.BaseURL+"/suppressions/{recipient}", wrapper.GetSuppression)
+	m.HandleFunc("GET "+options.BaseURL+"/templates", wrapper.GetTemplates)
+	m.HandleFunc("POST "+options.BaseURL+"/templates", wrapper.CreateTemplate)
+	m.HandleFunc("DELETE "+options.BaseURL+"/templates/{id}", wrapper.DeleteTemplate)
+	m.HandleFunc("GET "+options.BaseURL+"/templates/{id}", wrapper.GetTemplate)
+	m.HandleFunc("PUT "+options.BaseURL+"/templates/{id}", wrapper.UpdateTemplate)
+	m.HandleFunc("POST "+options.BaseURL+"/webhooks/delivery-receipts/{provider}", wrapper.ReceiveDeliveryReceipt)
+	m.HandleFunc("POST "+options.BaseURL+"/webhooks/inbound-messages/{provider}", wrapper.ReceiveInboundMessage)
 
 	return m
 }
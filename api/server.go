@@ -2,12 +2,22 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 )
 
+// maxBulkBodySize limits the size of bulk uploads
+const maxBulkBodySize = 10 << 20
+
+// maxIdempotencyKeyLength limits the length of the Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
 type Server struct {
 	DB           DBInterface
 	ResumePauser ResumePauser
+	Router       Router
+	MaxSegments  int // Messages longer than a single SMS are sent as up to this many parts, zero or one rejects them
 }
 
 //go:generate go tool mockgen --package=api --destination=mock_resume_pauser.go . ResumePauser
@@ -16,9 +26,38 @@ type ResumePauser interface {
 	Pause()
 }
 
+var (
+	// ErrNotFound is returned by DBInterface when a message does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned by DBInterface when a message cannot be changed in its current status
+	ErrConflict = errors.New("conflict")
+	// ErrIdempotencyKeyReused is returned by DBInterface when an idempotency key was already used
+	// to create a different message
+	ErrIdempotencyKeyReused = errors.New("Idempotency-Key was already used for a different request")
+)
+
 //go:generate go tool mockgen --package=api --destination=mock_db_interface.go . DBInterface
 type DBInterface interface {
-	GetSentMessages() ([]Message, error)
+	GetMessage(id int) (Message, error)
+	GetMessageByProviderMessageId(provider string, providerMessageId string) (Message, error)
+	GetMessages(filter MessageFilter) ([]Message, error)
+	GetSentMessages(filter MessageFilter) ([]Message, error)
+	InsertMessage(m NewMessage, idempotencyKey string) (Message, error)
+	InsertMessages(msgs []NewMessage, idempotencyKeys []string) ([]Message, error)
+	UpdateMessage(id int, update MessageUpdate) (Message, error)
+	CancelMessage(id int) (Message, error)
+	RecordDeliveryReceipt(provider string, receipt DeliveryReceipt) error
+	GetTemplates() ([]Template, error)
+	GetTemplate(id int) (Template, error)
+	InsertTemplate(t NewTemplate) (Template, error)
+	UpdateTemplate(id int, t NewTemplate) (Template, error)
+	DeleteTemplate(id int) error
+	GetSuppressions() ([]Suppression, error)
+	GetSuppression(recipient string) (Suppression, error)
+	InsertSuppression(s NewSuppression) (Suppression, error)
+	DeleteSuppression(recipient string) error
+	InsertInboundMessage(provider string, m NewInboundMessage, keyword *InboundKeyword) (InboundMessage, error)
+	GetInboundMessages(filter InboundMessageFilter) ([]InboundMessage, error)
 }
 
 func NewServer(database DBInterface, resumePauser ResumePauser) Server {
@@ -45,14 +84,346 @@ func (s Server) ChangeState(w http.ResponseWriter, r *http.Request, params Chang
 	_ = json.NewEncoder(w).Encode(State{Running: params.Action == Resume})
 }
 
-// GetSentMessages returns all sent messages
-func (s Server) GetSentMessages(w http.ResponseWriter, r *http.Request) {
-	msgs, err := s.DB.GetSentMessages()
+// GetSentMessages returns a page of sent messages
+func (s Server) GetSentMessages(w http.ResponseWriter, r *http.Request, params GetSentMessagesParams) {
+	limit, err := pageLimit(params.Limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	afterId, err := decodeCursor(params.After)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := MessageFilter{
+		SentFrom: params.SentFrom,
+		SentTo:   params.SentTo,
+		AfterId:  afterId,
+		Limit:    limit + 1, // one more to know if there is a next page
+	}
+	if params.Recipient != nil {
+		filter.Recipient = *params.Recipient
+	}
+
+	msgs, err := s.DB.GetSentMessages(filter)
 	if err != nil {
 		http.Error(w, "failed to fetch sent messages", http.StatusInternalServerError)
 		return
 	}
 
+	var resp SentMessagesResponse
+	resp.Messages, resp.NextCursor = paginate(msgs, limit, messageId)
+
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(msgs)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// GetMessages returns a page of messages in any status
+func (s Server) GetMessages(w http.ResponseWriter, r *http.Request, params GetMessagesParams) {
+	limit, err := pageLimit(params.Limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	afterId, err := decodeCursor(params.After)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := MessageFilter{
+		AfterId: afterId,
+		Limit:   limit + 1, // one more to know if there is a next page
+	}
+	if params.Status != nil {
+		for _, status := range *params.Status {
+			if !knownStatuses[status] {
+				http.Error(w, ErrInvalidStatus.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		filter.Statuses = *params.Status
+	}
+	if params.Recipient != nil {
+		filter.Recipient = *params.Recipient
+	}
+	if params.Order != nil {
+		switch *params.Order {
+		case Asc:
+		case Desc:
+			filter.Descending = true
+		default:
+			http.Error(w, ErrInvalidOrder.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	msgs, err := s.DB.GetMessages(filter)
+	if err != nil {
+		http.Error(w, "failed to fetch messages", http.StatusInternalServerError)
+		return
+	}
+
+	var resp MessagesResponse
+	resp.Messages, resp.NextCursor = paginate(msgs, limit, messageId)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// GetMessage returns a single message
+func (s Server) GetMessage(w http.ResponseWriter, r *http.Request, id MessageId) {
+	msg, err := s.DB.GetMessage(id)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to fetch message", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(msg)
+}
+
+// GetMessageByProviderMessageId returns the message the provider accepted under the given id
+func (s Server) GetMessageByProviderMessageId(w http.ResponseWriter, r *http.Request, providerMessageId string, params GetMessageByProviderMessageIdParams) {
+	var provider string
+	if params.Provider != nil {
+		provider = *params.Provider
+	}
+
+	msg, err := s.DB.GetMessageByProviderMessageId(provider, providerMessageId)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, ErrConflict) {
+		http.Error(w, "more than one provider accepted a message under this id, set the provider", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to fetch message", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(msg)
+}
+
+// UpdateMessage edits a message that has not been picked up by the dispatcher yet
+func (s Server) UpdateMessage(w http.ResponseWriter, r *http.Request, id MessageId) {
+	var body UpdateMessageJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	current, err := s.DB.GetMessage(id)
+	if err != nil {
+		writeMessageChangeError(w, err)
+		return
+	}
+	if current.Status != Unsent {
+		writeMessageChangeError(w, ErrConflict)
+		return
+	}
+
+	updated := NewMessage{
+		Content:     current.Content,
+		Recipient:   current.Recipient,
+		ScheduledAt: current.ScheduledAt,
+		Priority:    &current.Priority,
+		TimeZone:    current.TimeZone,
+	}
+	if body.Content != nil {
+		updated.Content = *body.Content
+	}
+	if body.Recipient != nil {
+		normalizeRecipient(body.Recipient)
+		updated.Recipient = *body.Recipient
+	}
+	if body.ScheduledAt != nil {
+		updated.ScheduledAt = body.ScheduledAt
+	}
+	if body.Priority != nil {
+		updated.Priority = body.Priority
+	}
+	if body.TimeZone != nil {
+		updated.TimeZone = body.TimeZone
+	}
+	if err := ValidateNewMessage(updated, s.MaxSegments); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	msg, err := s.DB.UpdateMessage(id, body)
+	if err != nil {
+		writeMessageChangeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(msg)
+}
+
+// CancelMessage cancels a message that has not been picked up by the dispatcher yet
+func (s Server) CancelMessage(w http.ResponseWriter, r *http.Request, id MessageId) {
+	msg, err := s.DB.CancelMessage(id)
+	if err != nil {
+		writeMessageChangeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(msg)
+}
+
+// writeMessageChangeError writes the response for an error returned while changing a message
+func writeMessageChangeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "message not found", http.StatusNotFound)
+	case errors.Is(err, ErrConflict):
+		http.Error(w, "message is not unsent anymore", http.StatusConflict)
+	default:
+		http.Error(w, "failed to update message", http.StatusInternalServerError)
+	}
+}
+
+// CreateMessage validates and stores a new unsent message
+func (s Server) CreateMessage(w http.ResponseWriter, r *http.Request, params CreateMessageParams) {
+	idempotencyKey, ok := validIdempotencyKey(w, params.IdempotencyKey)
+	if !ok {
+		return
+	}
+
+	var body CreateMessageJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := newTemplateRenderer(s.DB).render(&body)
+	if errors.Is(err, errFetchTemplate) {
+		http.Error(w, "failed to fetch template", http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		normalizeRecipient(&body.Recipient)
+		err = ValidateNewMessage(body, s.MaxSegments)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	msg, err := s.DB.InsertMessage(body, idempotencyKey)
+	if errors.Is(err, ErrIdempotencyKeyReused) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to create message", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(msg)
+}
+
+// CreateMessagesBulk validates every row of a CSV or NDJSON upload and stores the valid ones
+func (s Server) CreateMessagesBulk(w http.ResponseWriter, r *http.Request, params CreateMessagesBulkParams) {
+	idempotencyKey, ok := validIdempotencyKey(w, params.IdempotencyKey)
+	if !ok {
+		return
+	}
+
+	rows, err := parseBulkMessages(r.Header.Get("Content-Type"), http.MaxBytesReader(w, r.Body, maxBulkBodySize))
+	if errors.Is(err, ErrUnsupportedContentType) {
+		http.Error(w, "content type must be text/csv or application/x-ndjson", http.StatusUnsupportedMediaType)
+		return
+	}
+	if errors.As(err, new(*http.MaxBytesError)) {
+		http.Error(w, fmt.Sprintf("request body must not be larger than %d MB", maxBulkBodySize>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := BulkImportReport{Rows: make([]BulkImportRow, len(rows))}
+	var valid []NewMessage
+	var validIdx []int
+	var keys []string
+	renderer := newTemplateRenderer(s.DB)
+	for i, row := range rows {
+		report.Rows[i].Line = row.Line
+		if row.Err == nil {
+			row.Err = renderer.render(&row.Message)
+			if errors.Is(row.Err, errFetchTemplate) {
+				http.Error(w, "failed to fetch template", http.StatusInternalServerError)
+				return
+			}
+		}
+		if row.Err == nil {
+			normalizeRecipient(&row.Message.Recipient)
+			row.Err = ValidateNewMessage(row.Message, s.MaxSegments)
+		}
+		if row.Err != nil {
+			errMsg := row.Err.Error()
+			report.Rows[i].Error = &errMsg
+			report.Rejected++
+			continue
+		}
+		valid = append(valid, row.Message)
+		validIdx = append(validIdx, i)
+		if idempotencyKey != "" {
+			keys = append(keys, bulkIdempotencyKey(idempotencyKey, row.Line))
+		}
+	}
+
+	if len(valid) > 0 {
+		msgs, err := s.DB.InsertMessages(valid, keys)
+		if errors.Is(err, ErrIdempotencyKeyReused) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to create messages", http.StatusInternalServerError)
+			return
+		}
+		for i, msg := range msgs {
+			report.Rows[validIdx[i]].Message = &msg
+		}
+		report.Accepted = len(msgs)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// bulkIdempotencyKey derives the idempotency key of a row of a bulk upload. It starts with a NUL
+// byte, which header values cannot contain, so it never matches the key of a single message.
+func bulkIdempotencyKey(key string, line int) string {
+	return fmt.Sprintf("bulk\x00%s:%d", key, line)
+}
+
+// validIdempotencyKey returns the idempotency key or writes a 400 response if it is too long
+func validIdempotencyKey(w http.ResponseWriter, key *IdempotencyKey) (string, bool) {
+	if key == nil {
+		return "", true
+	}
+	if len(*key) > maxIdempotencyKeyLength {
+		http.Error(w, "Idempotency-Key must not be longer than 255 characters", http.StatusBadRequest)
+		return "", false
+	}
+	return *key, true
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -70,19 +71,20 @@ func TestServer_GetSentMessages(t *testing.T) {
 			{Id: 2, Content: "World!", Recipient: "+9876543210", Status: "sent", SentAt: &t1Parsed},
 		}
 
-		mockDB.EXPECT().GetSentMessages().Return(expectedMessages, nil)
+		mockDB.EXPECT().GetSentMessages(MessageFilter{Limit: DefaultPageLimit + 1}).Return(expectedMessages, nil)
 
 		r := httptest.NewRequest("GET", "/sent-messages", nil)
 		w := httptest.NewRecorder()
-		s.GetSentMessages(w, r)
+		s.GetSentMessages(w, r, GetSentMessagesParams{})
 
 		require.Equal(tt, http.StatusOK, w.Code)
 
-		var actualMessages []Message
-		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actualMessages))
-		require.Len(tt, actualMessages, 2)
-		require.Equal(tt, expectedMessages[0].Id, actualMessages[0].Id)
-		require.Equal(tt, expectedMessages[1].Content, actualMessages[1].Content)
+		var resp SentMessagesResponse
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+		require.Len(tt, resp.Messages, 2)
+		require.Equal(tt, expectedMessages[0].Id, resp.Messages[0].Id)
+		require.Equal(tt, expectedMessages[1].Content, resp.Messages[1].Content)
+		require.Nil(tt, resp.NextCursor)
 	})
 
 	t.Run("success - should return empty array if there are no messages", func(tt *testing.T) {
@@ -91,28 +93,756 @@ func TestServer_GetSentMessages(t *testing.T) {
 
 		expectedMessages := []Message{}
 
-		mockDB.EXPECT().GetSentMessages().Return(expectedMessages, nil)
+		mockDB.EXPECT().GetSentMessages(gomock.Any()).Return(expectedMessages, nil)
 
 		r := httptest.NewRequest("GET", "/sent-messages", nil)
 		w := httptest.NewRecorder()
-		s.GetSentMessages(w, r)
+		s.GetSentMessages(w, r, GetSentMessagesParams{})
 
 		require.Equal(tt, http.StatusOK, w.Code)
 
-		var actualMessages []Message
-		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actualMessages))
-		require.Equal(tt, expectedMessages, actualMessages)
+		var resp SentMessagesResponse
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+		require.Equal(tt, expectedMessages, resp.Messages)
+	})
+
+	t.Run("success - should pass the filters and return the cursor of the next page", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		limit := 2
+		after := encodeCursor(10)
+		recipient := "+1234567890"
+		sentFrom := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		sentTo := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+		mockDB.EXPECT().GetSentMessages(MessageFilter{
+			Recipient: recipient,
+			SentFrom:  &sentFrom,
+			SentTo:    &sentTo,
+			AfterId:   10,
+			Limit:     3,
+		}).Return([]Message{{Id: 11}, {Id: 12}, {Id: 13}}, nil)
+
+		r := httptest.NewRequest("GET", "/sent-messages", nil)
+		w := httptest.NewRecorder()
+		s.GetSentMessages(w, r, GetSentMessagesParams{
+			Limit:     &limit,
+			After:     &after,
+			Recipient: &recipient,
+			SentFrom:  &sentFrom,
+			SentTo:    &sentTo,
+		})
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var resp SentMessagesResponse
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+		require.Len(tt, resp.Messages, 2)
+		require.NotNil(tt, resp.NextCursor)
+
+		nextId, err := decodeCursor(resp.NextCursor)
+		require.NoError(tt, err)
+		require.Equal(tt, 12, nextId)
+	})
+
+	t.Run("error - should return 400 for invalid limit or cursor", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		limit := 0
+		w := httptest.NewRecorder()
+		s.GetSentMessages(w, httptest.NewRequest("GET", "/sent-messages", nil), GetSentMessagesParams{Limit: &limit})
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+
+		after := "not a cursor"
+		w = httptest.NewRecorder()
+		s.GetSentMessages(w, httptest.NewRequest("GET", "/sent-messages", nil), GetSentMessagesParams{After: &after})
+		require.Equal(tt, http.StatusBadRequest, w.Code)
 	})
 
 	t.Run("error - should return 500 on DB error", func(tt *testing.T) {
 		mockDB := NewMockDBInterface(ctrl)
 		s := Server{DB: mockDB}
 
-		mockDB.EXPECT().GetSentMessages().Return(nil, fmt.Errorf("dummy error"))
+		mockDB.EXPECT().GetSentMessages(gomock.Any()).Return(nil, fmt.Errorf("dummy error"))
 
 		r := httptest.NewRequest("GET", "/sent-messages", nil)
 		w := httptest.NewRecorder()
-		s.GetSentMessages(w, r)
+		s.GetSentMessages(w, r, GetSentMessagesParams{})
+
+		require.Equal(tt, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_GetMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should pass the filters and return a page of messages", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		limit := 1
+		statuses := []MessageStatus{Unsent, Invalid}
+		recipient := "+1234567890"
+		order := Desc
+
+		mockDB.EXPECT().GetMessages(MessageFilter{
+			Statuses:   statuses,
+			Recipient:  recipient,
+			Descending: true,
+			Limit:      2,
+		}).Return([]Message{{Id: 5, Status: Unsent}, {Id: 3, Status: Invalid}}, nil)
+
+		r := httptest.NewRequest("GET", "/messages", nil)
+		w := httptest.NewRecorder()
+		s.GetMessages(w, r, GetMessagesParams{Limit: &limit, Status: &statuses, Recipient: &recipient, Order: &order})
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var resp MessagesResponse
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+		require.Len(tt, resp.Messages, 1)
+		require.Equal(tt, 5, resp.Messages[0].Id)
+		require.NotNil(tt, resp.NextCursor)
+	})
+
+	t.Run("error - should return 400 for unknown statuses", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		statuses := []MessageStatus{"unknown"}
+		r := httptest.NewRequest("GET", "/messages", nil)
+		w := httptest.NewRecorder()
+		s.GetMessages(w, r, GetMessagesParams{Status: &statuses})
+
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("error - should return 500 on DB error", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetMessages(gomock.Any()).Return(nil, fmt.Errorf("dummy error"))
+
+		r := httptest.NewRequest("GET", "/messages", nil)
+		w := httptest.NewRecorder()
+		s.GetMessages(w, r, GetMessagesParams{})
+
+		require.Equal(tt, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_GetMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should return the message", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		expectedMessage := Message{Id: 3, Content: "Hello!", Recipient: "+1234567890", Status: Invalid}
+		mockDB.EXPECT().GetMessage(3).Return(expectedMessage, nil)
+
+		r := httptest.NewRequest("GET", "/messages/3", nil)
+		w := httptest.NewRecorder()
+		s.GetMessage(w, r, 3)
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var actualMessage Message
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actualMessage))
+		require.Equal(tt, expectedMessage, actualMessage)
+	})
+
+	t.Run("error - should return 404 if the message does not exist", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetMessage(3).Return(Message{}, ErrNotFound)
+
+		r := httptest.NewRequest("GET", "/messages/3", nil)
+		w := httptest.NewRecorder()
+		s.GetMessage(w, r, 3)
+
+		require.Equal(tt, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestServer_GetMessageByProviderMessageId(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should return the message", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		providerMessageId := "provider-1"
+		expectedMessage := Message{Id: 3, Content: "Hello!", Recipient: "+1234567890", Status: Delivered, ProviderMessageId: &providerMessageId}
+		mockDB.EXPECT().GetMessageByProviderMessageId("", providerMessageId).Return(expectedMessage, nil)
+
+		r := httptest.NewRequest("GET", "/messages/by-provider-id/provider-1", nil)
+		w := httptest.NewRecorder()
+		s.GetMessageByProviderMessageId(w, r, providerMessageId, GetMessageByProviderMessageIdParams{})
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var actualMessage Message
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actualMessage))
+		require.Equal(tt, expectedMessage, actualMessage)
+	})
+
+	t.Run("error - should return 404 if no message has the provider message id", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetMessageByProviderMessageId("", "provider-1").Return(Message{}, ErrNotFound)
+
+		r := httptest.NewRequest("GET", "/messages/by-provider-id/provider-1", nil)
+		w := httptest.NewRecorder()
+		s.GetMessageByProviderMessageId(w, r, "provider-1", GetMessageByProviderMessageIdParams{})
+
+		require.Equal(tt, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("success - should only look at the messages of the given provider", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		provider := "secondary"
+		mockDB.EXPECT().GetMessageByProviderMessageId(provider, "provider-1").Return(Message{Id: 3}, nil)
+
+		r := httptest.NewRequest("GET", "/messages/by-provider-id/provider-1?provider=secondary", nil)
+		w := httptest.NewRecorder()
+		s.GetMessageByProviderMessageId(w, r, "provider-1", GetMessageByProviderMessageIdParams{Provider: &provider})
+
+		require.Equal(tt, http.StatusOK, w.Code)
+	})
+
+	t.Run("error - should return 409 if more than one provider used the id", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetMessageByProviderMessageId("", "provider-1").Return(Message{}, ErrConflict)
+
+		r := httptest.NewRequest("GET", "/messages/by-provider-id/provider-1", nil)
+		w := httptest.NewRecorder()
+		s.GetMessageByProviderMessageId(w, r, "provider-1", GetMessageByProviderMessageIdParams{})
+
+		require.Equal(tt, http.StatusConflict, w.Code)
+	})
+}
+
+func TestServer_UpdateMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	current := Message{Id: 3, Content: "Hello!", Recipient: "+1234567890", Status: Unsent}
+
+	t.Run("success - should update the given fields and return the message", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		content := "Hello again!"
+		expectedMessage := current
+		expectedMessage.Content = content
+
+		mockDB.EXPECT().GetMessage(3).Return(current, nil)
+		mockDB.EXPECT().UpdateMessage(3, MessageUpdate{Content: &content}).Return(expectedMessage, nil)
+
+		r := httptest.NewRequest("PATCH", "/messages/3", strings.NewReader(`{"content":"Hello again!"}`))
+		w := httptest.NewRecorder()
+		s.UpdateMessage(w, r, 3)
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var actualMessage Message
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actualMessage))
+		require.Equal(tt, expectedMessage, actualMessage)
+	})
+
+	t.Run("error - should return 400 if the updated message is invalid", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetMessage(3).Return(current, nil)
+
+		r := httptest.NewRequest("PATCH", "/messages/3", strings.NewReader(`{"recipient":"not a number"}`))
+		w := httptest.NewRecorder()
+		s.UpdateMessage(w, r, 3)
+
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("error - should return 409 if the message was already picked up", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		sending := current
+		sending.Status = Sending
+		mockDB.EXPECT().GetMessage(3).Return(sending, nil)
+
+		r := httptest.NewRequest("PATCH", "/messages/3", strings.NewReader(`{"content":"Hello again!"}`))
+		w := httptest.NewRecorder()
+		s.UpdateMessage(w, r, 3)
+
+		require.Equal(tt, http.StatusConflict, w.Code)
+	})
+
+	t.Run("error - should return 409 if the message is picked up while updating", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetMessage(3).Return(current, nil)
+		mockDB.EXPECT().UpdateMessage(3, gomock.Any()).Return(Message{}, ErrConflict)
+
+		r := httptest.NewRequest("PATCH", "/messages/3", strings.NewReader(`{"content":"Hello again!"}`))
+		w := httptest.NewRecorder()
+		s.UpdateMessage(w, r, 3)
+
+		require.Equal(tt, http.StatusConflict, w.Code)
+	})
+
+	t.Run("error - should return 404 if the message does not exist", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetMessage(3).Return(Message{}, ErrNotFound)
+
+		r := httptest.NewRequest("PATCH", "/messages/3", strings.NewReader(`{"content":"Hello again!"}`))
+		w := httptest.NewRecorder()
+		s.UpdateMessage(w, r, 3)
+
+		require.Equal(tt, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestServer_CancelMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should cancel the message and return it", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().CancelMessage(3).Return(Message{Id: 3, Status: Cancelled}, nil)
+
+		r := httptest.NewRequest("DELETE", "/messages/3", nil)
+		w := httptest.NewRecorder()
+		s.CancelMessage(w, r, 3)
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var actualMessage Message
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actualMessage))
+		require.Equal(tt, Cancelled, actualMessage.Status)
+	})
+
+	t.Run("error - should return 409 if the message was already picked up", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().CancelMessage(3).Return(Message{}, ErrConflict)
+
+		r := httptest.NewRequest("DELETE", "/messages/3", nil)
+		w := httptest.NewRecorder()
+		s.CancelMessage(w, r, 3)
+
+		require.Equal(tt, http.StatusConflict, w.Code)
+	})
+
+	t.Run("error - should return 404 if the message does not exist", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().CancelMessage(3).Return(Message{}, ErrNotFound)
+
+		r := httptest.NewRequest("DELETE", "/messages/3", nil)
+		w := httptest.NewRecorder()
+		s.CancelMessage(w, r, 3)
+
+		require.Equal(tt, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestServer_CreateMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should store the message and return 201", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		newMessage := NewMessage{Content: "Hello!", Recipient: "+1234567890"}
+		expectedMessage := Message{Id: 7, Content: "Hello!", Recipient: "+1234567890", Status: Unsent}
+
+		mockDB.EXPECT().InsertMessage(newMessage, "").Return(expectedMessage, nil)
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"Hello!","recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+
+		var actualMessage Message
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actualMessage))
+		require.Equal(tt, expectedMessage, actualMessage)
+	})
+
+	t.Run("success - should normalize the recipient to E.164", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		newMessage := NewMessage{Content: "Hello!", Recipient: "+14181234567"}
+		mockDB.EXPECT().InsertMessage(newMessage, "").Return(Message{Id: 7, Content: "Hello!", Recipient: "+14181234567", Status: Unsent}, nil)
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"Hello!","recipient":"+1 (418) 123-4567"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+	})
+
+	t.Run("success - should accept a message that fits in a single UCS-2 SMS", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		content := strings.Repeat("ş", 69) + "!"
+		mockDB.EXPECT().InsertMessage(NewMessage{Content: content, Recipient: "+1234567890"}, "").Return(Message{Id: 7}, nil)
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"`+content+`","recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+	})
+
+	t.Run("success - should accept long messages up to the allowed number of segments", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB, MaxSegments: 2}
+
+		content := strings.Repeat("a", 306)
+		mockDB.EXPECT().InsertMessage(NewMessage{Content: content, Recipient: "+1234567890"}, "").Return(Message{Id: 7, Segments: 2}, nil)
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"`+content+`","recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+		require.Equal(tt, http.StatusCreated, w.Code)
+
+		r = httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"`+content+`a","recipient":"+1234567890"}`))
+		w = httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+		require.Contains(tt, w.Body.String(), "it takes 3 SMS parts, at most 2 are allowed")
+	})
+
+	t.Run("success - should pass the scheduled time to the DB", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		scheduledAt, err := time.Parse(time.RFC3339, "2025-05-31T09:00:00Z")
+		require.NoError(tt, err)
+
+		newMessage := NewMessage{Content: "Hello!", Recipient: "+1234567890", ScheduledAt: &scheduledAt}
+		mockDB.EXPECT().InsertMessage(newMessage, "").Return(Message{Id: 7, ScheduledAt: &scheduledAt}, nil)
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"Hello!","recipient":"+1234567890","scheduledAt":"2025-05-31T09:00:00Z"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+	})
+
+	t.Run("success - should pass the time zone to the DB", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		timeZone := "America/New_York"
+		newMessage := NewMessage{Content: "Hello!", Recipient: "+14181234567", TimeZone: &timeZone}
+		mockDB.EXPECT().InsertMessage(newMessage, "").Return(Message{Id: 7, TimeZone: &timeZone}, nil)
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"Hello!","recipient":"+14181234567","timeZone":"America/New_York"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+	})
+
+	t.Run("success - should pass the idempotency key to the DB", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		key := "dummy-key"
+		newMessage := NewMessage{Content: "Hello!", Recipient: "+1234567890"}
+		mockDB.EXPECT().InsertMessage(newMessage, key).Return(Message{Id: 7}, nil)
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"Hello!","recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{IdempotencyKey: &key})
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+	})
+
+	t.Run("error - should return 422 if the idempotency key was used for a different message", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		key := "dummy-key"
+		mockDB.EXPECT().InsertMessage(gomock.Any(), key).Return(Message{}, ErrIdempotencyKeyReused)
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"Hello!","recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{IdempotencyKey: &key})
+
+		require.Equal(tt, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("error - should return 400 if the idempotency key is too long", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		key := strings.Repeat("k", 256)
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"Hello!","recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{IdempotencyKey: &key})
+
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("error - should return 400 for malformed body", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("error - should return 400 for invalid messages", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		bodies := []string{
+			`{"content":"","recipient":"+1234567890"}`,
+			`{"content":"` + strings.Repeat("a", 161) + `","recipient":"+1234567890"}`,
+			`{"content":"` + strings.Repeat("ş", 71) + `","recipient":"+1234567890"}`,
+			`{"content":"` + strings.Repeat("€", 81) + `","recipient":"+1234567890"}`,
+			`{"content":"Hello!","recipient":""}`,
+			`{"content":"Hello!","recipient":"not a number"}`,
+			`{"content":"Hello!","recipient":"5551111111"}`,
+			`{"content":"Hello!","recipient":"+0905551111111"}`,
+			`{"content":"Hello!","recipient":"+1234567890","priority":11}`,
+			`{"content":"Hello!","recipient":"+1234567890","priority":-1}`,
+			`{"content":"Hello!","recipient":"+1234567890","timeZone":""}`,
+			`{"content":"Hello!","recipient":"+1234567890","timeZone":"Local"}`,
+			`{"content":"Hello!","recipient":"+1234567890","timeZone":"Mars/Olympus_Mons"}`,
+		}
+		for _, body := range bodies {
+			r := httptest.NewRequest("POST", "/messages", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			s.CreateMessage(w, r, CreateMessageParams{})
+
+			require.Equal(tt, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("error - should return 500 on DB error", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().InsertMessage(gomock.Any(), gomock.Any()).Return(Message{}, fmt.Errorf("dummy error"))
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"content":"Hello!","recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_CreateMessagesBulk(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should import valid CSV rows and report rejected ones", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		body := "recipient,content\n" +
+			"+905551111111,Hello!\n" +
+			"+905551111111," + strings.Repeat("a", 161) + "\n" +
+			"not a number,Hi\n" +
+			"+14181234567,\"Hi, there\"\n"
+
+		mockDB.EXPECT().InsertMessages([]NewMessage{
+			{Content: "Hello!", Recipient: "+905551111111"},
+			{Content: "Hi, there", Recipient: "+14181234567"},
+		}, nil).Return([]Message{
+			{Id: 1, Content: "Hello!", Recipient: "+905551111111", Status: Unsent},
+			{Id: 2, Content: "Hi, there", Recipient: "+14181234567", Status: Unsent},
+		}, nil)
+
+		r := httptest.NewRequest("POST", "/messages/bulk", strings.NewReader(body))
+		r.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		s.CreateMessagesBulk(w, r, CreateMessagesBulkParams{})
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var report BulkImportReport
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&report))
+		require.Equal(tt, 2, report.Accepted)
+		require.Equal(tt, 2, report.Rejected)
+		require.Len(tt, report.Rows, 4)
+
+		require.Equal(tt, 2, report.Rows[0].Line)
+		require.Equal(tt, 1, report.Rows[0].Message.Id)
+		require.Equal(tt, 3, report.Rows[1].Line)
+		require.Contains(tt, *report.Rows[1].Error, ErrContentTooLong.Error())
+		require.Equal(tt, 4, report.Rows[2].Line)
+		require.Equal(tt, ErrInvalidRecipient.Error(), *report.Rows[2].Error)
+		require.Equal(tt, 5, report.Rows[3].Line)
+		require.Equal(tt, 2, report.Rows[3].Message.Id)
+	})
+
+	t.Run("success - should import valid NDJSON rows and report rejected ones", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		body := `{"content":"Hello!","recipient":"+905551111111"}` + "\n" +
+			"\n" +
+			`{"content":` + "\n"
+
+		mockDB.EXPECT().InsertMessages([]NewMessage{
+			{Content: "Hello!", Recipient: "+905551111111"},
+		}, nil).Return([]Message{
+			{Id: 1, Content: "Hello!", Recipient: "+905551111111", Status: Unsent},
+		}, nil)
+
+		r := httptest.NewRequest("POST", "/messages/bulk", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-ndjson")
+		w := httptest.NewRecorder()
+		s.CreateMessagesBulk(w, r, CreateMessagesBulkParams{})
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var report BulkImportReport
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&report))
+		require.Equal(tt, 1, report.Accepted)
+		require.Equal(tt, 1, report.Rejected)
+		require.Len(tt, report.Rows, 2)
+		require.Equal(tt, 3, report.Rows[1].Line)
+		require.NotNil(tt, report.Rows[1].Error)
+	})
+
+	t.Run("success - should derive an idempotency key for every row from the line number", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		body := "content,recipient\nHello!,+905551111111\nHi,invalid\nHey,+905551111111\n"
+
+		mockDB.EXPECT().InsertMessages(gomock.Len(2), []string{"bulk\x00dummy-key:2", "bulk\x00dummy-key:4"}).Return([]Message{{Id: 1}, {Id: 2}}, nil)
+
+		key := "dummy-key"
+		r := httptest.NewRequest("POST", "/messages/bulk", strings.NewReader(body))
+		r.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		s.CreateMessagesBulk(w, r, CreateMessagesBulkParams{IdempotencyKey: &key})
+
+		require.Equal(tt, http.StatusOK, w.Code)
+	})
+
+	t.Run("success - should not reuse the key of a single message for a row", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		// a single message created with the key "dummy-key:2" must not be returned for line 2
+		mockDB.EXPECT().InsertMessages(gomock.Len(1), gomock.Any()).DoAndReturn(func(msgs []NewMessage, keys []string) ([]Message, error) {
+			require.NotEqual(tt, "dummy-key:2", keys[0])
+			return []Message{{Id: 1}}, nil
+		})
+
+		key := "dummy-key"
+		r := httptest.NewRequest("POST", "/messages/bulk", strings.NewReader("content,recipient\nHello!,+905551111111\n"))
+		r.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		s.CreateMessagesBulk(w, r, CreateMessagesBulkParams{IdempotencyKey: &key})
+
+		require.Equal(tt, http.StatusOK, w.Code)
+	})
+
+	t.Run("error - should return 422 if the idempotency key was used for a different file", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().InsertMessages(gomock.Len(1), gomock.Any()).Return(nil, ErrIdempotencyKeyReused)
+
+		key := "dummy-key"
+		r := httptest.NewRequest("POST", "/messages/bulk", strings.NewReader("content,recipient\nHello!,+905551111111\n"))
+		r.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		s.CreateMessagesBulk(w, r, CreateMessagesBulkParams{IdempotencyKey: &key})
+
+		require.Equal(tt, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("error - should return 413 if the upload is too large", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		row := "Hello!,+905551111111\n"
+		csvBody := "content,recipient\n" + strings.Repeat(row, maxBulkBodySize/len(row)+1)
+		ndjsonRow := `{"content":"Hello!","recipient":"+905551111111"}` + "\n"
+		ndjsonBody := strings.Repeat(ndjsonRow, maxBulkBodySize/len(ndjsonRow)+1)
+
+		for contentType, body := range map[string]string{"text/csv": csvBody, "application/x-ndjson": ndjsonBody} {
+			r := httptest.NewRequest("POST", "/messages/bulk", strings.NewReader(body))
+			r.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			s.CreateMessagesBulk(w, r, CreateMessagesBulkParams{})
+
+			require.Equal(tt, http.StatusRequestEntityTooLarge, w.Code, contentType)
+			require.Contains(tt, w.Body.String(), "10 MB", contentType)
+		}
+	})
+
+	t.Run("error - should return 415 for unsupported content types", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		r := httptest.NewRequest("POST", "/messages/bulk", strings.NewReader(`[]`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		s.CreateMessagesBulk(w, r, CreateMessagesBulkParams{})
+
+		require.Equal(tt, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	t.Run("error - should return 400 if CSV header is missing columns", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		r := httptest.NewRequest("POST", "/messages/bulk", strings.NewReader("content\nHello!\n"))
+		r.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		s.CreateMessagesBulk(w, r, CreateMessagesBulkParams{})
+
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("error - should return 500 on DB error", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().InsertMessages(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("dummy error"))
+
+		r := httptest.NewRequest("POST", "/messages/bulk", strings.NewReader("content,recipient\nHello!,+905551111111\n"))
+		r.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		s.CreateMessagesBulk(w, r, CreateMessagesBulkParams{})
 
 		require.Equal(tt, http.StatusInternalServerError, w.Code)
 	})
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/taylankasap/message-sender/phonenumber"
+)
+
+// GetSuppressions returns all suppressed recipients
+func (s Server) GetSuppressions(w http.ResponseWriter, r *http.Request) {
+	suppressions, err := s.DB.GetSuppressions()
+	if err != nil {
+		http.Error(w, "failed to fetch suppressions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(suppressions)
+}
+
+// CreateSuppression adds a recipient to the suppression list, so no more messages are sent to them
+func (s Server) CreateSuppression(w http.ResponseWriter, r *http.Request) {
+	var body CreateSuppressionJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	normalizeRecipient(&body.Recipient)
+	if !phonenumber.IsValid(body.Recipient) {
+		http.Error(w, ErrInvalidRecipient.Error(), http.StatusBadRequest)
+		return
+	}
+
+	suppression, err := s.DB.InsertSuppression(body)
+	if err != nil {
+		http.Error(w, "failed to store suppression", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(suppression)
+}
+
+// GetSuppression returns the suppression of a single recipient
+func (s Server) GetSuppression(w http.ResponseWriter, r *http.Request, recipient Recipient) {
+	normalizeRecipient(&recipient)
+	suppression, err := s.DB.GetSuppression(recipient)
+	if err != nil {
+		writeSuppressionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(suppression)
+}
+
+// DeleteSuppression removes a recipient from the suppression list
+func (s Server) DeleteSuppression(w http.ResponseWriter, r *http.Request, recipient Recipient) {
+	normalizeRecipient(&recipient)
+	if err := s.DB.DeleteSuppression(recipient); err != nil {
+		writeSuppressionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeSuppressionError writes the response for an error returned while accessing a suppression
+func writeSuppressionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "recipient is not suppressed", http.StatusNotFound)
+		return
+	}
+	http.Error(w, "failed to access suppression", http.StatusInternalServerError)
+}
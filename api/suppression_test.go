@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_GetSuppressions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should return the suppressed recipients", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		expected := []Suppression{{Recipient: "+905551111111", CreatedAt: time.Date(2025, 5, 31, 10, 0, 0, 0, time.UTC)}}
+		mockDB.EXPECT().GetSuppressions().Return(expected, nil)
+
+		r := httptest.NewRequest("GET", "/suppressions", nil)
+		w := httptest.NewRecorder()
+		s.GetSuppressions(w, r)
+
+		require.Equal(tt, http.StatusOK, w.Code)
+
+		var actual []Suppression
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actual))
+		require.Equal(tt, expected, actual)
+	})
+
+	t.Run("error - should return 500 on DB error", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetSuppressions().Return(nil, fmt.Errorf("dummy error"))
+
+		r := httptest.NewRequest("GET", "/suppressions", nil)
+		w := httptest.NewRecorder()
+		s.GetSuppressions(w, r)
+
+		require.Equal(tt, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_CreateSuppression(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should normalize the recipient and store the suppression", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		reason := "STOP"
+		mockDB.EXPECT().InsertSuppression(NewSuppression{Recipient: "+905551111111", Reason: &reason}).Return(Suppression{Recipient: "+905551111111", Reason: &reason}, nil)
+
+		r := httptest.NewRequest("POST", "/suppressions", strings.NewReader(`{"recipient":"+90 555 111 11 11","reason":"STOP"}`))
+		w := httptest.NewRecorder()
+		s.CreateSuppression(w, r)
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+	})
+
+	t.Run("error - should return 400 for invalid recipients", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		for _, body := range []string{`{`, `{"recipient":""}`, `{"recipient":"5551111111"}`} {
+			r := httptest.NewRequest("POST", "/suppressions", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			s.CreateSuppression(w, r)
+
+			require.Equal(tt, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("error - should return 500 on DB error", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().InsertSuppression(gomock.Any()).Return(Suppression{}, fmt.Errorf("dummy error"))
+
+		r := httptest.NewRequest("POST", "/suppressions", strings.NewReader(`{"recipient":"+905551111111"}`))
+		w := httptest.NewRecorder()
+		s.CreateSuppression(w, r)
+
+		require.Equal(tt, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_GetSuppression(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should return the suppression of the recipient", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetSuppression("+905551111111").Return(Suppression{Recipient: "+905551111111"}, nil)
+
+		r := httptest.NewRequest("GET", "/suppressions/+905551111111", nil)
+		w := httptest.NewRecorder()
+		s.GetSuppression(w, r, "+905551111111")
+
+		require.Equal(tt, http.StatusOK, w.Code)
+	})
+
+	t.Run("error - should return 404 if the recipient is not suppressed", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetSuppression("+905551111111").Return(Suppression{}, ErrNotFound)
+
+		r := httptest.NewRequest("GET", "/suppressions/+905551111111", nil)
+		w := httptest.NewRecorder()
+		s.GetSuppression(w, r, "+905551111111")
+
+		require.Equal(tt, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestServer_DeleteSuppression(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should remove the recipient from the suppression list", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().DeleteSuppression("+905551111111").Return(nil)
+
+		r := httptest.NewRequest("DELETE", "/suppressions/00905551111111", nil)
+		w := httptest.NewRecorder()
+		s.DeleteSuppression(w, r, "00905551111111")
+
+		require.Equal(tt, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("error - should return 404 if the recipient is not suppressed", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().DeleteSuppression("+905551111111").Return(ErrNotFound)
+
+		r := httptest.NewRequest("DELETE", "/suppressions/+905551111111", nil)
+		w := httptest.NewRecorder()
+		s.DeleteSuppression(w, r, "+905551111111")
+
+		require.Equal(tt, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("error - should return 500 on DB error", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().DeleteSuppression("+905551111111").Return(fmt.Errorf("dummy error"))
+
+		r := httptest.NewRequest("DELETE", "/suppressions/+905551111111", nil)
+		w := httptest.NewRecorder()
+		s.DeleteSuppression(w, r, "+905551111111")
+
+		require.Equal(tt, http.StatusInternalServerError, w.Code)
+	})
+}
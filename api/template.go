@@ -0,0 +1,246 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// maxRenderedLength limits the length of rendered templates, far above what fits in a message
+const maxRenderedLength = 64 << 10
+
+var (
+	ErrEmptyTemplateName   = errors.New("name must not be empty")
+	ErrEmptyTemplateBody   = errors.New("body must not be empty")
+	ErrContentWithTemplate = errors.New("content must not be given together with templateId")
+	ErrUnknownTemplate     = errors.New("template does not exist")
+	ErrTemplateAction      = errors.New("template may only reference variables, e.g. {{.name}}")
+	ErrRenderedTooLong     = errors.New("rendered template is too long")
+
+	// errFetchTemplate is returned by templateRenderer when the template could not be fetched
+	errFetchTemplate = errors.New("failed to fetch template")
+)
+
+// ValidateNewTemplate checks that a template can be stored and rendered later
+func ValidateNewTemplate(t NewTemplate) error {
+	if strings.TrimSpace(t.Name) == "" {
+		return ErrEmptyTemplateName
+	}
+	if t.Body == "" {
+		return ErrEmptyTemplateBody
+	}
+	if _, err := parseTemplate(t.Body); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseTemplate parses a template body. Only text and plain variables are allowed, since
+// functions such as printf and actions such as range could render huge messages.
+func parseTemplate(body string) (*template.Template, error) {
+	t, err := template.New("message").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	if len(t.Templates()) > 1 {
+		return nil, fmt.Errorf("invalid template: %w", ErrTemplateAction)
+	}
+	for _, node := range t.Tree.Root.Nodes {
+		if !isVariableOrText(node) {
+			return nil, fmt.Errorf("invalid template: %w", ErrTemplateAction)
+		}
+	}
+	return t, nil
+}
+
+// isVariableOrText reports whether the node is text or an action that only prints a variable
+func isVariableOrText(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.TextNode:
+		return true
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+			return false
+		}
+		field, ok := n.Pipe.Cmds[0].Args[0].(*parse.FieldNode)
+		return ok && len(field.Ident) == 1
+	default:
+		return false
+	}
+}
+
+// renderTemplate executes the template body with the given variables, a missing variable is an error
+func renderTemplate(body string, variables map[string]string) (string, error) {
+	t, err := parseTemplate(body)
+	if err != nil {
+		return "", err
+	}
+
+	content := &limitedBuilder{limit: maxRenderedLength}
+	if err := t.Execute(content, variables); err != nil {
+		if errors.Is(err, ErrRenderedTooLong) {
+			return "", ErrRenderedTooLong
+		}
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return content.String(), nil
+}
+
+// limitedBuilder is a strings.Builder that fails once more than limit bytes are written to it
+type limitedBuilder struct {
+	strings.Builder
+	limit int
+}
+
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, ErrRenderedTooLong
+	}
+	return b.Builder.Write(p)
+}
+
+// templateRenderer renders the content of new messages that reference a template,
+// fetching every template only once
+type templateRenderer struct {
+	db        DBInterface
+	templates map[int]Template
+}
+
+func newTemplateRenderer(db DBInterface) *templateRenderer {
+	return &templateRenderer{db: db, templates: map[int]Template{}}
+}
+
+// render sets the content of the message if it references a template. Errors wrapping
+// errFetchTemplate are server errors, any other error means the message is invalid.
+func (r *templateRenderer) render(m *NewMessage) error {
+	if m.TemplateId == nil {
+		return nil
+	}
+	if m.Content != "" {
+		return ErrContentWithTemplate
+	}
+
+	t, ok := r.templates[*m.TemplateId]
+	if !ok {
+		var err error
+		t, err = r.db.GetTemplate(*m.TemplateId)
+		if errors.Is(err, ErrNotFound) {
+			return ErrUnknownTemplate
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", errFetchTemplate, err)
+		}
+		r.templates[t.Id] = t
+	}
+
+	var variables map[string]string
+	if m.Variables != nil {
+		variables = *m.Variables
+	}
+
+	content, err := renderTemplate(t.Body, variables)
+	if err != nil {
+		return err
+	}
+	m.Content = content
+	return nil
+}
+
+// GetTemplates returns all templates
+func (s Server) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := s.DB.GetTemplates()
+	if err != nil {
+		http.Error(w, "failed to fetch templates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(templates)
+}
+
+// CreateTemplate validates and stores a new template
+func (s Server) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var body CreateTemplateJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateNewTemplate(body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := s.DB.InsertTemplate(body)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+// GetTemplate returns a single template
+func (s Server) GetTemplate(w http.ResponseWriter, r *http.Request, id TemplateId) {
+	t, err := s.DB.GetTemplate(id)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+// UpdateTemplate validates and replaces a template
+func (s Server) UpdateTemplate(w http.ResponseWriter, r *http.Request, id TemplateId) {
+	var body UpdateTemplateJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateNewTemplate(body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := s.DB.UpdateTemplate(id, body)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+// DeleteTemplate deletes a template
+func (s Server) DeleteTemplate(w http.ResponseWriter, r *http.Request, id TemplateId) {
+	if err := s.DB.DeleteTemplate(id); err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeTemplateError writes the response for an error returned while accessing a template
+func writeTemplateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "template not found", http.StatusNotFound)
+	case errors.Is(err, ErrConflict):
+		http.Error(w, "a template with the same name already exists", http.StatusConflict)
+	default:
+		http.Error(w, "failed to access template", http.StatusInternalServerError)
+	}
+}
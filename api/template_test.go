@@ -0,0 +1,267 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	t.Run("it should substitute the variables", func(tt *testing.T) {
+		content, err := renderTemplate("Hello {{.name}}, your code is {{.code}}", map[string]string{"name": "Jane", "code": "526184"})
+		require.NoError(tt, err)
+		require.Equal(tt, "Hello Jane, your code is 526184", content)
+	})
+
+	t.Run("it should fail if a variable is missing", func(tt *testing.T) {
+		_, err := renderTemplate("Hello {{.name}}", map[string]string{})
+		require.Error(tt, err)
+	})
+
+	t.Run("it should only allow variables", func(tt *testing.T) {
+		bodies := []string{
+			`{{printf "%999999999d" 1}}`,
+			`{{range .name}}{{range .name}}x{{end}}{{end}}`,
+			`{{if .name}}Hello{{end}}`,
+			`{{$x := .name}}`,
+			`{{.name | len}}`,
+			`{{.name.first}}`,
+			`{{define "other"}}Hello{{end}}`,
+			`{{template "message"}}`,
+		}
+		for _, body := range bodies {
+			_, err := renderTemplate(body, map[string]string{"name": "Jane"})
+			require.ErrorIs(tt, err, ErrTemplateAction, body)
+		}
+	})
+
+	t.Run("it should fail if the rendered content is too long", func(tt *testing.T) {
+		_, err := renderTemplate(strings.Repeat("{{.name}}", 100), map[string]string{"name": strings.Repeat("a", maxRenderedLength/50)})
+		require.ErrorIs(tt, err, ErrRenderedTooLong)
+	})
+}
+
+func TestServer_CreateMessage_Template(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	otp := Template{Id: 1, Name: "otp", Body: "Hello {{.name}}, your code is {{.code}}"}
+
+	t.Run("success - should render the template into the content", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetTemplate(1).Return(otp, nil)
+		mockDB.EXPECT().InsertMessage(gomock.Any(), "").DoAndReturn(func(m NewMessage, _ string) (Message, error) {
+			require.Equal(tt, "Hello Jane, your code is 526184", m.Content)
+			return Message{Id: 7, Content: m.Content}, nil
+		})
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"templateId":1,"variables":{"name":"Jane","code":"526184"},"recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+	})
+
+	t.Run("error - should return 400 if the rendered content is too long", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetTemplate(1).Return(otp, nil)
+
+		body := fmt.Sprintf(`{"templateId":1,"variables":{"name":"%s","code":"526184"},"recipient":"+1234567890"}`, strings.Repeat("a", 160))
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+		require.Contains(tt, w.Body.String(), ErrContentTooLong.Error())
+	})
+
+	t.Run("error - should return 400 if a variable is missing", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetTemplate(1).Return(otp, nil)
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"templateId":1,"variables":{"name":"Jane"},"recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("error - should return 400 if the template does not exist or content is also given", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetTemplate(2).Return(Template{}, ErrNotFound)
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"templateId":2,"recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+
+		r = httptest.NewRequest("POST", "/messages", strings.NewReader(`{"templateId":1,"content":"Hello!","recipient":"+1234567890"}`))
+		w = httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+		require.Equal(tt, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("error - should return 500 if the template cannot be fetched", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().GetTemplate(1).Return(Template{}, fmt.Errorf("dummy error"))
+
+		r := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"templateId":1,"recipient":"+1234567890"}`))
+		w := httptest.NewRecorder()
+		s.CreateMessage(w, r, CreateMessageParams{})
+
+		require.Equal(tt, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_CreateTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should store the template and return 201", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		newTemplate := NewTemplate{Name: "otp", Body: "Your code is {{.code}}"}
+		mockDB.EXPECT().InsertTemplate(newTemplate).Return(Template{Id: 1, Name: "otp", Body: "Your code is {{.code}}"}, nil)
+
+		r := httptest.NewRequest("POST", "/templates", strings.NewReader(`{"name":"otp","body":"Your code is {{.code}}"}`))
+		w := httptest.NewRecorder()
+		s.CreateTemplate(w, r)
+
+		require.Equal(tt, http.StatusCreated, w.Code)
+
+		var actualTemplate Template
+		require.NoError(tt, json.NewDecoder(w.Body).Decode(&actualTemplate))
+		require.Equal(tt, 1, actualTemplate.Id)
+	})
+
+	t.Run("error - should return 400 for invalid templates", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		bodies := []string{
+			`{"name":"","body":"Hello"}`,
+			`{"name":"otp","body":""}`,
+			`{"name":"otp","body":"Your code is {{.code"}`,
+			`{"name":"otp","body":"{{printf \"%999999999d\" 1}}"}`,
+		}
+		for _, body := range bodies {
+			r := httptest.NewRequest("POST", "/templates", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			s.CreateTemplate(w, r)
+
+			require.Equal(tt, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("error - should return 409 if the name is taken", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().InsertTemplate(gomock.Any()).Return(Template{}, ErrConflict)
+
+		r := httptest.NewRequest("POST", "/templates", strings.NewReader(`{"name":"otp","body":"Hello"}`))
+		w := httptest.NewRecorder()
+		s.CreateTemplate(w, r)
+
+		require.Equal(tt, http.StatusConflict, w.Code)
+	})
+}
+
+func TestServer_UpdateTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should replace the template", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().UpdateTemplate(1, NewTemplate{Name: "otp", Body: "Code: {{.code}}"}).Return(Template{Id: 1, Name: "otp", Body: "Code: {{.code}}"}, nil)
+
+		r := httptest.NewRequest("PUT", "/templates/1", strings.NewReader(`{"name":"otp","body":"Code: {{.code}}"}`))
+		w := httptest.NewRecorder()
+		s.UpdateTemplate(w, r, 1)
+
+		require.Equal(tt, http.StatusOK, w.Code)
+	})
+
+	t.Run("error - should return 404 if the template does not exist", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().UpdateTemplate(1, gomock.Any()).Return(Template{}, ErrNotFound)
+
+		r := httptest.NewRequest("PUT", "/templates/1", strings.NewReader(`{"name":"otp","body":"Code: {{.code}}"}`))
+		w := httptest.NewRecorder()
+		s.UpdateTemplate(w, r, 1)
+
+		require.Equal(tt, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestServer_GetTemplates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockDBInterface(ctrl)
+	s := Server{DB: mockDB}
+
+	mockDB.EXPECT().GetTemplates().Return([]Template{{Id: 1, Name: "otp", Body: "Code: {{.code}}"}}, nil)
+
+	r := httptest.NewRequest("GET", "/templates", nil)
+	w := httptest.NewRecorder()
+	s.GetTemplates(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var templates []Template
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&templates))
+	require.Len(t, templates, 1)
+}
+
+func TestServer_DeleteTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("success - should delete the template and return 204", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().DeleteTemplate(1).Return(nil)
+
+		r := httptest.NewRequest("DELETE", "/templates/1", nil)
+		w := httptest.NewRecorder()
+		s.DeleteTemplate(w, r, 1)
+
+		require.Equal(tt, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("error - should return 404 if the template does not exist", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		s := Server{DB: mockDB}
+
+		mockDB.EXPECT().DeleteTemplate(1).Return(ErrNotFound)
+
+		r := httptest.NewRequest("DELETE", "/templates/1", nil)
+		w := httptest.NewRecorder()
+		s.DeleteTemplate(w, r, 1)
+
+		require.Equal(tt, http.StatusNotFound, w.Code)
+	})
+}
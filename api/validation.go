@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/taylankasap/message-sender/phonenumber"
+	"github.com/taylankasap/message-sender/sms"
+)
+
+// Messages with a higher priority are sent first
+const (
+	MinPriority = 0
+	MaxPriority = 10
+)
+
+var (
+	ErrEmptyContent     = errors.New("content must not be empty")
+	ErrContentTooLong   = errors.New("content is too long")
+	ErrInvalidRecipient = errors.New("recipient must be a phone number in E.164 format, e.g. +905551111111")
+	ErrInvalidPriority  = errors.New("priority must be between 0 and 10")
+	ErrInvalidTimeZone  = errors.New("time zone must be an IANA time zone, e.g. Europe/Istanbul")
+)
+
+// ValidateNewMessage checks that a message can be accepted for sending, see ValidateSegments for maxSegments
+func ValidateNewMessage(m NewMessage, maxSegments int) error {
+	if m.Content == "" {
+		return ErrEmptyContent
+	}
+	if _, err := ValidateSegments(m.Content, maxSegments); err != nil {
+		return err
+	}
+	if !phonenumber.IsValid(m.Recipient) {
+		return ErrInvalidRecipient
+	}
+	if m.Priority != nil && (*m.Priority < MinPriority || *m.Priority > MaxPriority) {
+		return ErrInvalidPriority
+	}
+	if m.TimeZone != nil && !IsValidTimeZone(*m.TimeZone) {
+		return ErrInvalidTimeZone
+	}
+	return nil
+}
+
+// IsValidTimeZone reports whether the name is a time zone in the IANA database. The empty
+// name and "Local" are rejected, since they mean UTC and the time zone of the server.
+func IsValidTimeZone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// ValidateSegments checks that the content can be sent in at most maxSegments SMS parts as a
+// concatenated SMS. Zero or one only allows content that fits in a single SMS.
+func ValidateSegments(content string, maxSegments int) (sms.Segmentation, error) {
+	segmentation := sms.Segment(content)
+	if maxSegments <= 1 && segmentation.Segments > 1 {
+		return segmentation, fmt.Errorf("%w: it must fit in a single SMS (%d GSM-7 or %d UCS-2 characters)",
+			ErrContentTooLong, sms.GSM7SingleLength, sms.UCS2SingleLength)
+	}
+	if maxSegments > 1 && segmentation.Segments > maxSegments {
+		return segmentation, fmt.Errorf("%w: it takes %d SMS parts, at most %d are allowed",
+			ErrContentTooLong, segmentation.Segments, maxSegments)
+	}
+	return segmentation, nil
+}
+
+// normalizeRecipient rewrites the recipient in E.164 format. Malformed numbers are left as
+// they are, so they are rejected by ValidateNewMessage.
+func normalizeRecipient(recipient *string) {
+	if normalized, err := phonenumber.Normalize(*recipient); err == nil {
+		*recipient = normalized
+	}
+}
+
+// knownStatuses are the statuses a message can be in
+var knownStatuses = map[MessageStatus]bool{
+	Sent:        true,
+	Unsent:      true,
+	Sending:     true,
+	Invalid:     true,
+	Failed:      true,
+	Cancelled:   true,
+	Delivered:   true,
+	Undelivered: true,
+	Suppressed:  true,
+}
@@ -0,0 +1,180 @@
+// Package config loads the settings of the app from a YAML file, an optional profile file
+// for the environment and environment variables, in this order of precedence
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables that override the settings
+const EnvPrefix = "MESSAGE_SENDER_"
+
+type Config struct {
+	Database   Database   `yaml:"database"`
+	Server     Server     `yaml:"server"`
+	Redis      Redis      `yaml:"redis"`
+	Providers  []Provider `yaml:"providers"`
+	Routing    Routing    `yaml:"routing"`
+	Dispatcher Dispatcher `yaml:"dispatcher"`
+}
+
+type Database struct {
+	Filename string `yaml:"filename"`
+	Seed     bool   `yaml:"seed"` // Insert sample messages into an empty database on start-up
+}
+
+type Server struct {
+	Addr string `yaml:"addr"` // e.g. 0.0.0.0:8080
+}
+
+type Redis struct {
+	Addr string `yaml:"addr"` // Empty to run without Redis
+}
+
+// Provider is a third party the messages are sent with, in the order of the list
+type Provider struct {
+	Name      string  `yaml:"name"`
+	BaseURL   string  `yaml:"baseUrl"`
+	RateLimit float64 `yaml:"rateLimit"` // Messages per second, zero for no limit
+	Burst     int     `yaml:"burst"`     // Messages that can be sent at once before the rate limit applies
+}
+
+// Routing picks the providers of a recipient by the longest route prefix it starts with. Routes
+// replaced at runtime are stored in the database and used instead of these from then on.
+type Routing struct {
+	DefaultProviders []string `yaml:"defaultProviders"` // Empty to use every provider, in the order of the list
+	Routes           []Route  `yaml:"routes"`
+}
+
+type Route struct {
+	Prefix    string   `yaml:"prefix"`    // e.g. +90
+	Providers []string `yaml:"providers"` // Tried in this order
+}
+
+// Dispatcher holds the settings of the message dispatcher, see MessageDispatcherConfig
+type Dispatcher struct {
+	Period        time.Duration `yaml:"period"`
+	BatchSize     int           `yaml:"batchSize"`
+	PriorityShare float64       `yaml:"priorityShare"`
+	MaxAttempts   int           `yaml:"maxAttempts"`
+	BaseBackoff   time.Duration `yaml:"baseBackoff"`
+	MaxBackoff    time.Duration `yaml:"maxBackoff"`
+	SendTimeout   time.Duration `yaml:"sendTimeout"`
+	MaxSegments   int           `yaml:"maxSegments"` // Also limits the messages accepted by the API
+
+	FailureThreshold int           `yaml:"failureThreshold"`
+	ProviderCooldown time.Duration `yaml:"providerCooldown"`
+
+	FrequencyCaps []FrequencyCap `yaml:"frequencyCaps"`
+	QuietHours    *QuietHours    `yaml:"quietHours"` // Null to send messages at any time
+}
+
+type FrequencyCap struct {
+	Limit  int           `yaml:"limit"`
+	Window time.Duration `yaml:"window"`
+}
+
+type QuietHours struct {
+	Start          time.Duration `yaml:"start"` // Time of day, e.g. 21h
+	End            time.Duration `yaml:"end"`   // Time of day, e.g. 9h
+	ExemptPriority int           `yaml:"exemptPriority"`
+}
+
+// Load reads the config file and, if a profile is given, the file of the profile next to it,
+// e.g. config.production.yaml for config.yaml. Settings in the profile file replace the ones in
+// the config file, and environment variables replace both (see applyEnv). The result is validated.
+func Load(filename, profile string) (*Config, error) {
+	var cfg Config
+	if err := decodeFile(filename, &cfg); err != nil {
+		return nil, err
+	}
+
+	if profile != "" {
+		ext := filepath.Ext(filename)
+		profileFilename := strings.TrimSuffix(filename, ext) + "." + profile + ext
+		if err := decodeFile(profileFilename, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(&cfg, EnvPrefix, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// decodeFile decodes a YAML file on top of the given config, keeping the settings the file
+// does not mention. Unknown settings are rejected, since they are most likely typos.
+func decodeFile(filename string, cfg *Config) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", filename, err)
+	}
+	return nil
+}
+
+// Validate checks that the settings make sense, and returns every problem it finds
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Database.Filename != "", "database.filename must not be empty")
+	check(c.Server.Addr != "", "server.addr must not be empty")
+
+	check(len(c.Providers) > 0, "at least one provider must be configured")
+	names := map[string]bool{}
+	for i, p := range c.Providers {
+		check(p.Name != "", "providers[%d].name must not be empty", i)
+		check(!names[p.Name], "providers[%d].name must be unique, %s is used more than once", i, p.Name)
+		check(p.BaseURL != "", "providers[%d].baseUrl must not be empty", i)
+		check(p.RateLimit >= 0, "providers[%d].rateLimit must not be negative", i)
+		check(p.Burst >= 0, "providers[%d].burst must not be negative", i)
+		names[p.Name] = true
+	}
+
+	d := c.Dispatcher
+	check(d.Period > 0, "dispatcher.period must be positive")
+	check(d.BatchSize > 0, "dispatcher.batchSize must be positive")
+	check(d.PriorityShare >= 0 && d.PriorityShare <= 1, "dispatcher.priorityShare must be between 0 and 1")
+	check(d.MaxAttempts >= 0, "dispatcher.maxAttempts must not be negative")
+	check(d.BaseBackoff >= 0, "dispatcher.baseBackoff must not be negative")
+	check(d.MaxBackoff >= 0, "dispatcher.maxBackoff must not be negative")
+	check(d.SendTimeout >= 0, "dispatcher.sendTimeout must not be negative")
+	check(d.MaxSegments >= 0, "dispatcher.maxSegments must not be negative")
+	check(d.FailureThreshold >= 0, "dispatcher.failureThreshold must not be negative")
+	check(d.ProviderCooldown >= 0, "dispatcher.providerCooldown must not be negative")
+	for i, fc := range d.FrequencyCaps {
+		check(fc.Limit > 0, "dispatcher.frequencyCaps[%d].limit must be positive", i)
+		check(fc.Window > 0, "dispatcher.frequencyCaps[%d].window must be positive", i)
+	}
+	if q := d.QuietHours; q != nil {
+		check(q.Start >= 0 && q.Start <= 24*time.Hour, "dispatcher.quietHours.start must be a time of day between 0h and 24h")
+		check(q.End >= 0 && q.End <= 24*time.Hour, "dispatcher.quietHours.end must be a time of day between 0h and 24h")
+		check(q.ExemptPriority >= 0, "dispatcher.quietHours.exemptPriority must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("success - should load the default config", func(tt *testing.T) {
+		cfg, err := Load("config.yaml", "")
+		require.NoError(tt, err)
+		require.Equal(tt, "data/db.sqlite3", cfg.Database.Filename)
+		require.True(tt, cfg.Database.Seed)
+		require.Equal(tt, "0.0.0.0:8080", cfg.Server.Addr)
+		require.Equal(tt, "redis:6379", cfg.Redis.Addr)
+		require.Equal(tt, []Provider{{Name: "somethirdparty", BaseURL: "https://webhook.site/e8318d16-f749-428e-9103-f1ca43e8c0dd", RateLimit: 10, Burst: 20}}, cfg.Providers)
+		require.Equal(tt, Routing{DefaultProviders: []string{}, Routes: []Route{}}, cfg.Routing)
+		require.Equal(tt, 5*time.Second, cfg.Dispatcher.Period)
+		require.Equal(tt, 20, cfg.Dispatcher.BatchSize)
+		require.Equal(tt, []FrequencyCap{{Limit: 10, Window: 24 * time.Hour}}, cfg.Dispatcher.FrequencyCaps)
+		require.Equal(tt, &QuietHours{Start: 21 * time.Hour, End: 9 * time.Hour, ExemptPriority: 5}, cfg.Dispatcher.QuietHours)
+	})
+
+	t.Run("success - should apply the profile on top of the default config", func(tt *testing.T) {
+		cfg, err := Load("config.yaml", "development")
+		require.NoError(tt, err)
+		require.Equal(tt, "localhost:6379", cfg.Redis.Addr)
+		require.Equal(tt, time.Second, cfg.Dispatcher.Period)
+		require.Nil(tt, cfg.Dispatcher.QuietHours)
+
+		// settings the profile does not mention are kept
+		require.Equal(tt, 20, cfg.Dispatcher.BatchSize)
+		require.Len(tt, cfg.Providers, 1)
+
+		cfg, err = Load("config.yaml", "production")
+		require.NoError(tt, err)
+		require.False(tt, cfg.Database.Seed)
+		require.Equal(tt, 100, cfg.Dispatcher.BatchSize)
+	})
+
+	t.Run("success - should apply environment variables on top of the files", func(tt *testing.T) {
+		tt.Setenv("MESSAGE_SENDER_REDIS_ADDR", "")
+		tt.Setenv("MESSAGE_SENDER_DISPATCHER_BATCH_SIZE", "50")
+		tt.Setenv("MESSAGE_SENDER_DISPATCHER_PERIOD", "30s")
+		tt.Setenv("MESSAGE_SENDER_PROVIDERS_0_BASE_URL", "https://example.com")
+		tt.Setenv("MESSAGE_SENDER_DISPATCHER_QUIET_HOURS_START", "22h")
+
+		cfg, err := Load("config.yaml", "production")
+		require.NoError(tt, err)
+		require.Empty(tt, cfg.Redis.Addr)
+		require.Equal(tt, 50, cfg.Dispatcher.BatchSize)
+		require.Equal(tt, 30*time.Second, cfg.Dispatcher.Period)
+		require.Equal(tt, "https://example.com", cfg.Providers[0].BaseURL)
+		require.Equal(tt, 22*time.Hour, cfg.Dispatcher.QuietHours.Start)
+		require.Equal(tt, 9*time.Hour, cfg.Dispatcher.QuietHours.End)
+	})
+
+	t.Run("error - should reject malformed environment variables", func(tt *testing.T) {
+		tt.Setenv("MESSAGE_SENDER_DISPATCHER_BATCH_SIZE", "many")
+
+		_, err := Load("config.yaml", "")
+		require.ErrorContains(tt, err, "MESSAGE_SENDER_DISPATCHER_BATCH_SIZE")
+	})
+
+	t.Run("error - should fail if the profile does not exist", func(tt *testing.T) {
+		_, err := Load("config.yaml", "staging")
+		require.Error(tt, err)
+	})
+
+	t.Run("error - should reject unknown settings", func(tt *testing.T) {
+		filename := filepath.Join(tt.TempDir(), "config.yaml")
+		require.NoError(tt, os.WriteFile(filename, []byte("dispatcher:\n  batchsize: 10\n"), 0o600))
+
+		_, err := Load(filename, "")
+		require.ErrorContains(tt, err, "batchsize")
+	})
+
+	t.Run("error - should validate the config", func(tt *testing.T) {
+		filename := filepath.Join(tt.TempDir(), "config.yaml")
+		require.NoError(tt, os.WriteFile(filename, []byte("dispatcher:\n  batchSize: 10\n"), 0o600))
+
+		_, err := Load(filename, "")
+		require.ErrorContains(tt, err, "database.filename must not be empty")
+		require.ErrorContains(tt, err, "at least one provider must be configured")
+		require.ErrorContains(tt, err, "dispatcher.period must be positive")
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() Config {
+		return Config{
+			Database:  Database{Filename: "db.sqlite3"},
+			Server:    Server{Addr: ":8080"},
+			Providers: []Provider{{Name: "primary", BaseURL: "https://example.com"}},
+			Dispatcher: Dispatcher{
+				Period:        time.Minute,
+				BatchSize:     1,
+				FrequencyCaps: []FrequencyCap{{Limit: 1, Window: time.Hour}},
+				QuietHours:    &QuietHours{Start: 21 * time.Hour, End: 9 * time.Hour},
+			},
+		}
+	}
+
+	t.Run("success - should accept a valid config", func(tt *testing.T) {
+		cfg := valid()
+		require.NoError(tt, cfg.Validate())
+	})
+
+	t.Run("error - should reject invalid settings", func(tt *testing.T) {
+		cases := map[string]func(c *Config){
+			"providers[1].name must be unique":              func(c *Config) { c.Providers = append(c.Providers, c.Providers[0]) },
+			"providers[0].baseUrl must not be empty":        func(c *Config) { c.Providers[0].BaseURL = "" },
+			"providers[0].rateLimit must not be negative":   func(c *Config) { c.Providers[0].RateLimit = -1 },
+			"server.addr must not be empty":                 func(c *Config) { c.Server.Addr = "" },
+			"dispatcher.batchSize must be positive":         func(c *Config) { c.Dispatcher.BatchSize = 0 },
+			"dispatcher.priorityShare must be between":      func(c *Config) { c.Dispatcher.PriorityShare = 1.5 },
+			"dispatcher.frequencyCaps[0].limit":             func(c *Config) { c.Dispatcher.FrequencyCaps[0].Limit = 0 },
+			"dispatcher.quietHours.start must be a time":    func(c *Config) { c.Dispatcher.QuietHours.Start = 25 * time.Hour },
+			"dispatcher.quietHours.exemptPriority must not": func(c *Config) { c.Dispatcher.QuietHours.ExemptPriority = -1 },
+		}
+		for expected, change := range cases {
+			cfg := valid()
+			change(&cfg)
+			require.ErrorContains(tt, cfg.Validate(), expected)
+		}
+	})
+}
+
+func TestEnvName(t *testing.T) {
+	require.Equal(t, "BASE_URL", envName("baseUrl"))
+	require.Equal(t, "QUIET_HOURS", envName("quietHours"))
+	require.Equal(t, "ADDR", envName("addr"))
+}
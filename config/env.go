@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv replaces the settings that have an environment variable set. The name of the
+// variable is the path of the setting in the YAML file in upper snake case after the prefix,
+// e.g. MESSAGE_SENDER_DISPATCHER_BATCH_SIZE for dispatcher.batchSize. Items of lists are
+// addressed by their index, e.g. MESSAGE_SENDER_PROVIDERS_0_BASE_URL, so only items that exist
+// in the files can be changed. Durations use the Go syntax, e.g. 2m30s.
+func applyEnv(cfg *Config, prefix string, lookup func(string) (string, bool)) error {
+	_, err := applyEnvValue(reflect.ValueOf(cfg).Elem(), strings.TrimSuffix(prefix, "_"), lookup)
+	return err
+}
+
+// applyEnvValue sets the value, or the fields or items it contains, from the variables named
+// after it and returns whether any of them was set
+func applyEnvValue(v reflect.Value, name string, lookup func(string) (string, bool)) (bool, error) {
+	switch v.Kind() {
+	case reflect.Struct:
+		changed := false
+		for i := 0; i < v.NumField(); i++ {
+			tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+			fieldChanged, err := applyEnvValue(v.Field(i), name+"_"+envName(tag), lookup)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || fieldChanged
+		}
+		return changed, nil
+
+	case reflect.Slice:
+		changed := false
+		for i := 0; i < v.Len(); i++ {
+			itemChanged, err := applyEnvValue(v.Index(i), name+"_"+strconv.Itoa(i), lookup)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || itemChanged
+		}
+		return changed, nil
+
+	case reflect.Pointer:
+		// optional sections missing from the files are only added if a variable sets them
+		target := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			target.Elem().Set(v.Elem())
+		}
+		changed, err := applyEnvValue(target.Elem(), name, lookup)
+		if changed {
+			v.Set(target)
+		}
+		return changed, err
+	}
+
+	raw, ok := lookup(name)
+	if !ok {
+		return false, nil
+	}
+	if err := setValue(v, raw); err != nil {
+		return false, fmt.Errorf("invalid value of %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// setValue parses the raw value of an environment variable into a setting
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(i))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// envName turns a camel case YAML key into upper snake case, e.g. baseUrl into BASE_URL
+func envName(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
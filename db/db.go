@@ -1,14 +1,20 @@
 package db
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/taylankasap/message-sender/api"
+	"github.com/taylankasap/message-sender/sms"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 type Database struct {
@@ -36,9 +42,123 @@ func New(cfg *Config) (*Database, error) {
 		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
 
+	if err := addMissingColumns(db, "message", messageColumnMigrations); err != nil {
+		return nil, fmt.Errorf("failed to migrate table: %w", err)
+	}
+
+	_, err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS message_idempotency_key ON message (idempotency_key)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	// provider message ids are only unique per provider
+	_, err = db.Exec("DROP INDEX IF EXISTS message_provider_message_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to drop index: %w", err)
+	}
+	_, err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS message_provider_provider_message_id ON message (provider, provider_message_id)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	if err := createTemplateTable(db); err != nil {
+		return nil, fmt.Errorf("failed to create template table: %w", err)
+	}
+
+	if err := createSuppressionTable(db); err != nil {
+		return nil, fmt.Errorf("failed to create suppression table: %w", err)
+	}
+
+	if err := createInboundMessageTable(db); err != nil {
+		return nil, fmt.Errorf("failed to create inbound message table: %w", err)
+	}
+
+	if err := createRoutingTable(db); err != nil {
+		return nil, fmt.Errorf("failed to create routing table: %w", err)
+	}
+
 	return &Database{Conn: db}, nil
 }
 
+// column is a column added to a table after it was first created
+type column struct {
+	Name       string
+	Definition string
+}
+
+// messageColumnMigrations lists the columns added to the message table over time, in order
+var messageColumnMigrations = []column{
+	{Name: "scheduled_at", Definition: "DATETIME"},
+	{Name: "priority", Definition: "INTEGER NOT NULL DEFAULT 0"},
+	{Name: "attempts", Definition: "INTEGER NOT NULL DEFAULT 0"},
+	{Name: "next_attempt_at", Definition: "DATETIME"},
+	{Name: "last_error", Definition: "TEXT"},
+	{Name: "idempotency_key", Definition: "TEXT"},
+	{Name: "segments", Definition: "INTEGER NOT NULL DEFAULT 1"},
+	{Name: "provider_message_id", Definition: "TEXT"},
+	{Name: "delivered_at", Definition: "DATETIME"},
+	{Name: "provider", Definition: "TEXT"},
+	{Name: "time_zone", Definition: "TEXT"},
+	{Name: "claimed_at", Definition: "DATETIME"},
+	{Name: "idempotency_hash", Definition: "TEXT"},
+}
+
+// addMissingColumns adds the given columns to the table if they do not exist yet
+func addMissingColumns(db *sql.DB, table string, columns []column) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range columns {
+		if existing[c.Name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, c.Name, c.Definition)); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// messageColumns is the list of columns scanned by scanMessage
+const messageColumns = "id, content, recipient, status, sent_at, scheduled_at, priority, attempts, next_attempt_at, last_error, segments, provider, provider_message_id, delivered_at, time_zone"
+
+// scanMessage scans a row selected with messageColumns
+func scanMessage(row interface{ Scan(dest ...any) error }) (api.Message, error) {
+	var m api.Message
+	err := row.Scan(&m.Id, &m.Content, &m.Recipient, &m.Status, &m.SentAt, &m.ScheduledAt, &m.Priority, &m.Attempts, &m.NextAttemptAt, &m.LastError, &m.Segments, &m.Provider, &m.ProviderMessageId, &m.DeliveredAt, &m.TimeZone)
+	return m, err
+}
+
+// formatTime formats an optional time the way it is stored in the database
+func formatTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatClaim formats the time a message was claimed at. It identifies the claim, so unlike
+// other times it is stored with its fractional seconds.
+func formatClaim(claimedAt time.Time) string {
+	return claimedAt.UTC().Format(time.RFC3339Nano)
+}
+
 // Seed inserts initial messages if the table is empty
 func (d *Database) Seed() error {
 	row := d.Conn.QueryRow("SELECT COUNT(*) FROM message")
@@ -54,20 +174,33 @@ func (d *Database) Seed() error {
 		return nil // already seeded
 	}
 
-	_, err = d.Conn.Exec(`INSERT INTO message (content, recipient, status, sent_at) VALUES
-		('Huge sale :)', '+905551234567', $1, '2024-02-12T03:00:06+03:00'),
-		('Insider - Project', '+905551111111', $2, NULL),
-		('Tiny sale :(', '+905551234567', $1, '2025-05-30T21:17:09+07:00'),
-		('Hello universe!', '+14181234567', $2, NULL),
-		('You can use this one time password to log in to somewhere: 526184', '+821260542022', $2, NULL),
-		('Check out our products!', '+821251876804', $2, NULL)
-	`, api.Sent, api.Unsent)
+	_, err = d.Conn.Exec(`INSERT INTO message (content, recipient, status, sent_at, priority) VALUES
+		('Huge sale :)', '+905551234567', $1, '2024-02-12T03:00:06+03:00', 0),
+		('Insider - Project', '+905551111111', $2, NULL, 0),
+		('Tiny sale :(', '+905551234567', $1, '2025-05-30T21:17:09+07:00', 0),
+		('Hello universe!', '+14181234567', $2, NULL, 0),
+		('You can use this one time password to log in to somewhere: 526184', '+821260542022', $2, NULL, $3),
+		('Check out our products!', '+821251876804', $2, NULL, 0)
+	`, api.Sent, api.Unsent, api.MaxPriority)
 	return err
 }
 
-// GetUnsentMessages fetches up to n unsent messages from the database
+// GetUnsentMessages fetches up to n unsent messages that are due, highest priority first
 func (d *Database) GetUnsentMessages(limit int) ([]api.Message, error) {
-	rows, err := d.Conn.Query("SELECT id, content, recipient, status, sent_at FROM message WHERE status = $1 ORDER BY id ASC LIMIT $2", api.Unsent, limit)
+	return d.getDueMessages("priority DESC, id ASC", limit)
+}
+
+// GetOldestUnsentMessages fetches up to n unsent messages that are due, oldest first regardless of priority
+func (d *Database) GetOldestUnsentMessages(limit int) ([]api.Message, error) {
+	return d.getDueMessages("id ASC", limit)
+}
+
+func (d *Database) getDueMessages(orderBy string, limit int) ([]api.Message, error) {
+	now := time.Now()
+	rows, err := d.Conn.Query(
+		"SELECT "+messageColumns+" FROM message WHERE status = $1 AND (scheduled_at IS NULL OR scheduled_at <= $2) AND (next_attempt_at IS NULL OR next_attempt_at <= $2) ORDER BY "+orderBy+" LIMIT $3",
+		api.Unsent, formatTime(&now), limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -75,8 +208,7 @@ func (d *Database) GetUnsentMessages(limit int) ([]api.Message, error) {
 
 	var messages []api.Message
 	for rows.Next() {
-		var m api.Message
-		err := rows.Scan(&m.Id, &m.Content, &m.Recipient, &m.Status, &m.SentAt)
+		m, err := scanMessage(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -86,9 +218,53 @@ func (d *Database) GetUnsentMessages(limit int) ([]api.Message, error) {
 	return messages, nil
 }
 
-// GetSentMessages fetches all sent messages from the database
-func (d *Database) GetSentMessages() ([]api.Message, error) {
-	rows, err := d.Conn.Query("SELECT id, content, recipient, status, sent_at FROM message WHERE status = $1 ORDER BY id ASC", api.Sent)
+// GetSentMessages fetches sent messages matching the filter from the database
+func (d *Database) GetSentMessages(filter api.MessageFilter) ([]api.Message, error) {
+	filter.Statuses = []api.MessageStatus{api.Sent, api.Delivered, api.Undelivered}
+	return d.GetMessages(filter)
+}
+
+// GetMessages fetches messages matching the filter from the database, ordered by id
+func (d *Database) GetMessages(filter api.MessageFilter) ([]api.Message, error) {
+	query := "SELECT " + messageColumns + " FROM message WHERE 1 = 1"
+	var args []any
+
+	if len(filter.Statuses) > 0 {
+		query += " AND status IN (?" + strings.Repeat(", ?", len(filter.Statuses)-1) + ")"
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if filter.Recipient != "" {
+		query += " AND recipient = ?"
+		args = append(args, filter.Recipient)
+	}
+	// sent_at may be stored with any offset, datetime() converts it to UTC before comparing
+	if filter.SentFrom != nil {
+		query += " AND datetime(sent_at) >= datetime(?)"
+		args = append(args, formatTime(filter.SentFrom))
+	}
+	if filter.SentTo != nil {
+		query += " AND datetime(sent_at) < datetime(?)"
+		args = append(args, formatTime(filter.SentTo))
+	}
+
+	order := "ASC"
+	if filter.Descending {
+		order = "DESC"
+		if filter.AfterId != 0 {
+			query += " AND id < ?"
+			args = append(args, filter.AfterId)
+		}
+	} else if filter.AfterId != 0 {
+		query += " AND id > ?"
+		args = append(args, filter.AfterId)
+	}
+
+	query += " ORDER BY id " + order + " LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := d.Conn.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -96,8 +272,7 @@ func (d *Database) GetSentMessages() ([]api.Message, error) {
 
 	messages := []api.Message{}
 	for rows.Next() {
-		var m api.Message
-		err := rows.Scan(&m.Id, &m.Content, &m.Recipient, &m.Status, &m.SentAt)
+		m, err := scanMessage(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -107,17 +282,411 @@ func (d *Database) GetSentMessages() ([]api.Message, error) {
 	return messages, nil
 }
 
-// MarkMessageAsSent updates the status and sent_at fields for a message
-func (d *Database) MarkMessageAsSent(id int, sentAt time.Time) error {
+// GetMessage fetches a single message from the database, or returns api.ErrNotFound
+func (d *Database) GetMessage(id int) (api.Message, error) {
+	m, err := scanMessage(d.Conn.QueryRow("SELECT "+messageColumns+" FROM message WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.Message{}, api.ErrNotFound
+	}
+	return m, err
+}
+
+// GetMessageByProviderMessageId fetches the message the provider accepted under the given id,
+// or returns api.ErrNotFound. An empty provider matches any provider, in which case it returns
+// api.ErrConflict if more than one provider accepted a message under the id.
+func (d *Database) GetMessageByProviderMessageId(provider string, providerMessageId string) (api.Message, error) {
+	query := "SELECT " + messageColumns + " FROM message WHERE provider_message_id = ?"
+	args := []any{providerMessageId}
+	if provider != "" {
+		query += " AND provider = ?"
+		args = append(args, provider)
+	}
+
+	rows, err := d.Conn.Query(query+" LIMIT 2", args...)
+	if err != nil {
+		return api.Message{}, err
+	}
+	defer rows.Close()
+
+	var messages []api.Message
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return api.Message{}, err
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return api.Message{}, err
+	}
+
+	switch len(messages) {
+	case 0:
+		return api.Message{}, api.ErrNotFound
+	case 1:
+		return messages[0], nil
+	default:
+		return api.Message{}, api.ErrConflict
+	}
+}
+
+// InsertMessage stores a new unsent message and returns it. If a message was already
+// created with the same non-empty idempotency key, that message is returned instead, or
+// api.ErrIdempotencyKeyReused if it was created from a different message.
+func (d *Database) InsertMessage(m api.NewMessage, idempotencyKey string) (api.Message, error) {
+	return insertMessage(d.Conn, m, idempotencyKey)
+}
+
+// InsertMessages stores multiple unsent messages in a single transaction. Idempotency keys
+// are optional and, when given, must be in the same order as the messages. Like InsertMessage,
+// it returns api.ErrIdempotencyKeyReused if a key was used for a different message, and then
+// stores none of them.
+func (d *Database) InsertMessages(msgs []api.NewMessage, idempotencyKeys []string) ([]api.Message, error) {
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	created := make([]api.Message, 0, len(msgs))
+	for i, m := range msgs {
+		var key string
+		if i < len(idempotencyKeys) {
+			key = idempotencyKeys[i]
+		}
+		msg, err := insertMessage(tx, m, key)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, msg)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// insertMessage inserts a message using either a connection or a transaction
+func insertMessage(q querier, m api.NewMessage, idempotencyKey string) (api.Message, error) {
+	var key, hash any
+	if idempotencyKey != "" {
+		h, err := messageHash(m)
+		if err != nil {
+			return api.Message{}, err
+		}
+		existing, err := messageByIdempotencyKey(q, idempotencyKey, h)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return existing, err
+		}
+		key, hash = idempotencyKey, h
+	}
+
+	priority := 0
+	if m.Priority != nil {
+		priority = *m.Priority
+	}
+
+	segments := sms.Segment(m.Content).Segments
+	res, err := q.Exec(
+		"INSERT INTO message (content, recipient, status, scheduled_at, priority, segments, time_zone, idempotency_key, idempotency_hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		m.Content, m.Recipient, api.Unsent, formatTime(m.ScheduledAt), priority, segments, m.TimeZone, key, hash,
+	)
+	var sqliteErr sqlite3.Error
+	if key != nil && errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		// a concurrent request with the same key got there first
+		return messageByIdempotencyKey(q, idempotencyKey, hash.(string))
+	}
+	if err != nil {
+		return api.Message{}, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return api.Message{}, err
+	}
+
+	return api.Message{
+		Id:          int(id),
+		Content:     m.Content,
+		Recipient:   m.Recipient,
+		Status:      api.Unsent,
+		ScheduledAt: m.ScheduledAt,
+		Priority:    priority,
+		Segments:    segments,
+		TimeZone:    m.TimeZone,
+	}, nil
+}
+
+// messageByIdempotencyKey returns the message created with the idempotency key, or
+// api.ErrIdempotencyKeyReused if it was created from a message with a different hash. Messages
+// created before the hash was stored are assumed to match.
+func messageByIdempotencyKey(q querier, idempotencyKey string, hash string) (api.Message, error) {
+	var storedHash sql.NullString
+	err := q.QueryRow("SELECT idempotency_hash FROM message WHERE idempotency_key = ?", idempotencyKey).Scan(&storedHash)
+	if err != nil {
+		return api.Message{}, err
+	}
+	if storedHash.Valid && storedHash.String != hash {
+		return api.Message{}, api.ErrIdempotencyKeyReused
+	}
+	return scanMessage(q.QueryRow("SELECT "+messageColumns+" FROM message WHERE idempotency_key = ?", idempotencyKey))
+}
+
+// messageHash identifies the message a request asked for, so a reused idempotency key can be
+// told apart from a retry
+func messageHash(m api.NewMessage) (string, error) {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// UpdateMessage changes the given fields of an unsent message and returns the updated message.
+// It returns api.ErrConflict if the message is not unsent anymore.
+func (d *Database) UpdateMessage(id int, update api.MessageUpdate) (api.Message, error) {
+	var sets []string
+	var args []any
+	if update.Content != nil {
+		sets = append(sets, "content = ?", "segments = ?")
+		args = append(args, *update.Content, sms.Segment(*update.Content).Segments)
+	}
+	if update.Recipient != nil {
+		sets = append(sets, "recipient = ?")
+		args = append(args, *update.Recipient)
+	}
+	if update.ScheduledAt != nil {
+		sets = append(sets, "scheduled_at = ?")
+		args = append(args, formatTime(update.ScheduledAt))
+	}
+	if update.Priority != nil {
+		sets = append(sets, "priority = ?")
+		args = append(args, *update.Priority)
+	}
+	if update.TimeZone != nil {
+		sets = append(sets, "time_zone = ?")
+		args = append(args, *update.TimeZone)
+	}
+	if len(sets) == 0 {
+		sets = append(sets, "id = id")
+	}
+
+	args = append(args, id, api.Unsent)
+	res, err := d.Conn.Exec("UPDATE message SET "+strings.Join(sets, ", ")+" WHERE id = ? AND status = ?", args...)
+	if err != nil {
+		return api.Message{}, err
+	}
+
+	return d.changedMessage(id, res)
+}
+
+// CancelMessage updates the status of an unsent message to cancelled and returns it.
+// It returns api.ErrConflict if the message is not unsent anymore.
+func (d *Database) CancelMessage(id int) (api.Message, error) {
+	res, err := d.Conn.Exec("UPDATE message SET status = ? WHERE id = ? AND status = ?", api.Cancelled, id, api.Unsent)
+	if err != nil {
+		return api.Message{}, err
+	}
+
+	return d.changedMessage(id, res)
+}
+
+// changedMessage returns the message after a conditional update, api.ErrNotFound if it does
+// not exist or api.ErrConflict if it exists but was not updated
+func (d *Database) changedMessage(id int, res sql.Result) (api.Message, error) {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return api.Message{}, err
+	}
+
+	m, err := d.GetMessage(id)
+	if err != nil {
+		return api.Message{}, err
+	}
+	if affected == 0 {
+		return api.Message{}, api.ErrConflict
+	}
+
+	return m, nil
+}
+
+// MarkMessageAsSent updates the status and sent_at fields for a message and stores the provider
+// that sent it and the id the provider returned for it, which delivery receipts refer to. An
+// empty id is stored as NULL, since it cannot identify the message. Like every update of a
+// message being sent, it only applies while the message is still in sending under the claim
+// returned by MarkMessageAsSending, so a send that outlived its claim cannot overwrite the message.
+func (d *Database) MarkMessageAsSent(id int, claimedAt time.Time, sentAt time.Time, provider string, providerMessageId string) error {
 	_, err := d.Conn.Exec(
-		"UPDATE message SET status = ?, sent_at = ? WHERE id = ?",
-		api.Sent, sentAt.Format(time.RFC3339), id,
+		"UPDATE message SET status = ?, sent_at = ?, provider = ?, provider_message_id = NULLIF(?, '') WHERE id = ? AND status = ? AND claimed_at = ?",
+		api.Sent, sentAt.Format(time.RFC3339), provider, providerMessageId, id, api.Sending, formatClaim(claimedAt),
 	)
 	return err
 }
 
-// MarkMessageAsInvalid updates the status of a message to invalid
-func (d *Database) MarkMessageAsInvalid(id int) error {
-	_, err := d.Conn.Exec("UPDATE message SET status = ? WHERE id = ?", api.Invalid, id)
+// RecordDeliveryReceipt updates the status of the message the provider sent under the given
+// provider message id to delivered or undelivered. An undelivered message can still be delivered
+// later, but a delivered message stays delivered. Receipts that do not change the status are
+// ignored, because providers may send them more than once. It returns api.ErrNotFound if the
+// provider sent no message with the id and api.ErrConflict if the message is not sent.
+func (d *Database) RecordDeliveryReceipt(provider string, receipt api.DeliveryReceipt) error {
+	sets := "status = ?"
+	args := []any{receipt.Status}
+	from := []any{api.Sent}
+	if receipt.Status == api.Delivered {
+		sets += ", delivered_at = ?"
+		args = append(args, formatTime(receipt.DeliveredAt))
+		from = append(from, api.Undelivered)
+	}
+	if receipt.Error != nil {
+		sets += ", last_error = ?"
+		args = append(args, *receipt.Error)
+	}
+
+	args = append(args, provider, receipt.MessageId)
+	args = append(args, from...)
+	res, err := d.Conn.Exec(
+		"UPDATE message SET "+sets+" WHERE provider = ? AND provider_message_id = ? AND status IN (?"+strings.Repeat(", ?", len(from)-1)+")",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var status api.MessageStatus
+	err = d.Conn.QueryRow("SELECT status FROM message WHERE provider = ? AND provider_message_id = ?", provider, receipt.MessageId).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status == receipt.Status || status == api.Delivered {
+		return nil
+	}
+	return api.ErrConflict
+}
+
+// GetSentTimes fetches the times messages were sent to the recipient since the given time, oldest first
+func (d *Database) GetSentTimes(recipient string, since time.Time) ([]time.Time, error) {
+	rows, err := d.Conn.Query(
+		"SELECT sent_at FROM message WHERE recipient = ? AND status IN (?, ?, ?) AND datetime(sent_at) >= datetime(?) ORDER BY datetime(sent_at) ASC",
+		recipient, api.Sent, api.Delivered, api.Undelivered, formatTime(&since),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var times []time.Time
+	for rows.Next() {
+		var sentAt time.Time
+		if err := rows.Scan(&sentAt); err != nil {
+			return nil, err
+		}
+		times = append(times, sentAt)
+	}
+
+	return times, rows.Err()
+}
+
+// DeferMessage puts a message that is being sent back in the queue until the given time,
+// without counting it as a failed attempt
+func (d *Database) DeferMessage(id int, claimedAt time.Time, until time.Time) error {
+	_, err := d.Conn.Exec(
+		"UPDATE message SET status = ?, next_attempt_at = ?, claimed_at = NULL WHERE id = ? AND status = ? AND claimed_at = ?",
+		api.Unsent, formatTime(&until), id, api.Sending, formatClaim(claimedAt),
+	)
+	return err
+}
+
+// MarkMessageAsInvalid updates the status of a message that is being sent to invalid and
+// records why it is invalid. Messages in any other status, e.g. cancelled ones, are left as they are.
+func (d *Database) MarkMessageAsInvalid(id int, claimedAt time.Time, reason string) error {
+	_, err := d.Conn.Exec(
+		"UPDATE message SET status = ?, last_error = ? WHERE id = ? AND status = ? AND claimed_at = ?",
+		api.Invalid, reason, id, api.Sending, formatClaim(claimedAt),
+	)
+	return err
+}
+
+// MarkMessageAsSending updates the status of an unsent message to sending and returns the
+// message as it is when claimed, since it may have been updated after it was fetched, and the
+// time it was claimed at, which the updates recording the outcome of the send must pass. It
+// returns false if the message was not unsent anymore, e.g. because it was already picked up.
+func (d *Database) MarkMessageAsSending(id int) (api.Message, time.Time, bool, error) {
+	claimedAt := time.Now()
+	m, err := scanMessage(d.Conn.QueryRow(
+		"UPDATE message SET status = ?, claimed_at = ? WHERE id = ? AND status = ? RETURNING "+messageColumns,
+		api.Sending, formatClaim(claimedAt), id, api.Unsent,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.Message{}, time.Time{}, false, nil
+	}
+	if err != nil {
+		return api.Message{}, time.Time{}, false, err
+	}
+
+	return m, claimedAt, true, nil
+}
+
+// ResetSendingMessages puts the messages that were claimed before the given time and are still
+// in sending back in the queue and returns how many were updated. They were interrupted while
+// being sent, e.g. because the app stopped, so this counts as an attempt, and messages that
+// reached maxAttempts are marked as failed instead. A zero maxAttempts retries forever. Their
+// claims are dropped, so the outcome of a send that is still running is not recorded.
+func (d *Database) ResetSendingMessages(claimedBefore time.Time, maxAttempts int) (int, error) {
+	res, err := d.Conn.Exec(
+		`UPDATE message SET
+			status = CASE WHEN ? > 0 AND attempts + 1 >= ? THEN ? ELSE ? END,
+			attempts = attempts + 1,
+			last_error = ?,
+			next_attempt_at = NULL,
+			claimed_at = NULL
+		WHERE status = ? AND (claimed_at IS NULL OR datetime(claimed_at) <= datetime(?))`,
+		maxAttempts, maxAttempts, api.Failed, api.Unsent, "interrupted while sending", api.Sending, formatTime(&claimedBefore),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+	return int(affected), err
+}
+
+// RecordFailedAttempt counts a failed send attempt and puts the message back in the queue
+// until nextAttemptAt. provider is the provider the message must be retried with because the
+// outcome of the attempt with it is unknown, or empty. Once set it is kept for every later
+// attempt, since a failure of a later attempt does not tell whether the earlier one succeeded.
+func (d *Database) RecordFailedAttempt(id int, claimedAt time.Time, lastError string, nextAttemptAt time.Time, provider string) error {
+	_, err := d.Conn.Exec(
+		"UPDATE message SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ?, provider = COALESCE(NULLIF(?, ''), provider), claimed_at = NULL WHERE id = ? AND status = ? AND claimed_at = ?",
+		api.Unsent, lastError, formatTime(&nextAttemptAt), provider, id, api.Sending, formatClaim(claimedAt),
+	)
+	return err
+}
+
+// MarkMessageAsFailed counts a failed send attempt and updates the status of a message to failed
+func (d *Database) MarkMessageAsFailed(id int, claimedAt time.Time, lastError string) error {
+	_, err := d.Conn.Exec(
+		"UPDATE message SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = NULL WHERE id = ? AND status = ? AND claimed_at = ?",
+		api.Failed, lastError, id, api.Sending, formatClaim(claimedAt),
+	)
 	return err
 }
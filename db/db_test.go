@@ -1,7 +1,9 @@
 package db_test
 
 import (
+	"database/sql"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,6 +33,39 @@ func TestNew(t *testing.T) {
 		require.NoError(tt, err)
 		require.Equal(tt, "message", tableName)
 	})
+
+	t.Run("it should add missing columns to a 'message' table created by an older version", func(tt *testing.T) {
+		testFile := "test_db_migrate.sqlite3"
+		_ = os.Remove(testFile)
+
+		conn, err := sql.Open("sqlite3", testFile)
+		require.NoError(tt, err)
+		_, err = conn.Exec(`CREATE TABLE message (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			content TEXT NOT NULL,
+			recipient TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'unsent',
+			sent_at DATETIME
+		)`)
+		require.NoError(tt, err)
+		require.NoError(tt, conn.Close())
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		_, err = database.GetUnsentMessages(1)
+		require.NoError(tt, err)
+
+		// opening it again should not try to add the columns twice
+		again, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		again.Conn.Close()
+	})
 }
 
 func TestDatabase_GetUnsentMessages(t *testing.T) {
@@ -59,9 +94,79 @@ func TestDatabase_GetUnsentMessages(t *testing.T) {
 	})
 }
 
-func TestDatabase_MarkMessageAsSent(t *testing.T) {
-	t.Run("it should mark a message as sent and set sent_at", func(tt *testing.T) {
-		testFile := "test_db_mark_sent.sqlite3"
+func TestDatabase_GetUnsentMessages_Scheduled(t *testing.T) {
+	t.Run("it should only fetch scheduled messages whose time has passed", func(tt *testing.T) {
+		testFile := "test_db_fetch_scheduled.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		past := time.Now().Add(-time.Hour)
+		future := time.Now().Add(time.Hour)
+		_, err = database.InsertMessages([]api.NewMessage{
+			{Content: "Later", Recipient: "+905551111111", ScheduledAt: &future},
+			{Content: "Due", Recipient: "+905551111111", ScheduledAt: &past},
+			{Content: "Now", Recipient: "+905551111111"},
+		}, nil)
+		require.NoError(tt, err)
+
+		msgs, err := database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 2)
+		require.Equal(tt, "Due", msgs[0].Content)
+		require.WithinDuration(tt, past, *msgs[0].ScheduledAt, time.Second)
+		require.Equal(tt, "Now", msgs[1].Content)
+		require.Nil(tt, msgs[1].ScheduledAt)
+	})
+}
+
+func TestDatabase_GetUnsentMessages_Priority(t *testing.T) {
+	t.Run("it should fetch higher priority messages first, then the oldest", func(tt *testing.T) {
+		testFile := "test_db_fetch_priority.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		high := api.MaxPriority
+		_, err = database.InsertMessages([]api.NewMessage{
+			{Content: "Marketing 1", Recipient: "+905551111111"},
+			{Content: "Marketing 2", Recipient: "+905551111111"},
+			{Content: "OTP", Recipient: "+905551111111", Priority: &high},
+		}, nil)
+		require.NoError(tt, err)
+
+		msgs, err := database.GetUnsentMessages(2)
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 2)
+		require.Equal(tt, "OTP", msgs[0].Content)
+		require.Equal(tt, api.MaxPriority, msgs[0].Priority)
+		require.Equal(tt, "Marketing 1", msgs[1].Content)
+
+		msgs, err = database.GetOldestUnsentMessages(2)
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 2)
+		require.Equal(tt, "Marketing 1", msgs[0].Content)
+		require.Equal(tt, "Marketing 2", msgs[1].Content)
+	})
+}
+
+func TestDatabase_GetSentMessages(t *testing.T) {
+	t.Run("it should filter and paginate sent messages", func(tt *testing.T) {
+		testFile := "test_db_fetch_sent.sqlite3"
 		_ = os.Remove(testFile)
 
 		database, err := db.New(&db.Config{Filename: testFile})
@@ -73,31 +178,47 @@ func TestDatabase_MarkMessageAsSent(t *testing.T) {
 			_ = os.Remove(testFile)
 		}()
 
+		// seeds two sent messages to +905551234567, on 2024-02-12 and 2025-05-30
 		require.NoError(tt, database.Seed())
 
-		var id int
-		row := database.Conn.QueryRow("SELECT id FROM message WHERE status = ?", api.Unsent)
-		require.NoError(tt, row.Scan(&id))
+		msgs, err := database.GetSentMessages(api.MessageFilter{Limit: 10})
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 2)
+		for _, m := range msgs {
+			require.Equal(tt, api.Sent, m.Status)
+		}
 
-		expectedSentAt := time.Now()
-		err = database.MarkMessageAsSent(id, expectedSentAt)
+		page, err := database.GetSentMessages(api.MessageFilter{Limit: 1})
 		require.NoError(tt, err)
+		require.Len(tt, page, 1)
+		require.Equal(tt, msgs[0].Id, page[0].Id)
 
-		var actualStatus api.MessageStatus
-		var actualSentAt string
-		row = database.Conn.QueryRow("SELECT status, sent_at FROM message WHERE id = ?", id)
-		require.NoError(tt, row.Scan(&actualStatus, &actualSentAt))
-		require.Equal(tt, api.Sent, actualStatus)
+		page, err = database.GetSentMessages(api.MessageFilter{AfterId: msgs[0].Id, Limit: 1})
+		require.NoError(tt, err)
+		require.Len(tt, page, 1)
+		require.Equal(tt, msgs[1].Id, page[0].Id)
 
-		parsedSentAt, err := time.Parse(time.RFC3339, actualSentAt)
+		from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		page, err = database.GetSentMessages(api.MessageFilter{SentFrom: &from, Limit: 10})
 		require.NoError(tt, err)
-		require.WithinDuration(tt, expectedSentAt.UTC(), parsedSentAt.UTC(), time.Second)
+		require.Len(tt, page, 1)
+		require.Equal(tt, msgs[1].Id, page[0].Id)
+
+		// 2024-02-12T03:00:06+03:00 is 2024-02-12T00:00:06Z
+		to := time.Date(2024, 2, 12, 0, 0, 6, 0, time.UTC)
+		page, err = database.GetSentMessages(api.MessageFilter{SentTo: &to, Limit: 10})
+		require.NoError(tt, err)
+		require.Empty(tt, page)
+
+		page, err = database.GetSentMessages(api.MessageFilter{Recipient: "+905551111111", Limit: 10})
+		require.NoError(tt, err)
+		require.Empty(tt, page)
 	})
 }
 
-func TestDatabase_MarkMessageAsInvalid(t *testing.T) {
-	t.Run("it should mark a message as invalid", func(tt *testing.T) {
-		testFile := "test_db_mark_invalid.sqlite3"
+func TestDatabase_GetMessages(t *testing.T) {
+	t.Run("it should filter by status and paginate in both directions", func(tt *testing.T) {
+		testFile := "test_db_fetch_messages.sqlite3"
 		_ = os.Remove(testFile)
 
 		database, err := db.New(&db.Config{Filename: testFile})
@@ -111,16 +232,903 @@ func TestDatabase_MarkMessageAsInvalid(t *testing.T) {
 
 		require.NoError(tt, database.Seed())
 
-		var id int
-		row := database.Conn.QueryRow("SELECT id FROM message WHERE status = ?", api.Unsent)
-		require.NoError(tt, row.Scan(&id))
+		all, err := database.GetMessages(api.MessageFilter{Limit: 10})
+		require.NoError(tt, err)
+		require.Len(tt, all, 6)
+
+		require.NoError(tt, database.MarkMessageAsInvalid(all[1].Id, claim(tt, database, all[1].Id), "dummy reason"))
 
-		err = database.MarkMessageAsInvalid(id)
+		msgs, err := database.GetMessages(api.MessageFilter{Statuses: []api.MessageStatus{api.Unsent, api.Invalid}, Limit: 10})
 		require.NoError(tt, err)
+		require.Len(tt, msgs, 4)
+		require.Equal(tt, api.Invalid, msgs[0].Status)
 
-		var actualStatus api.MessageStatus
-		row = database.Conn.QueryRow("SELECT status FROM message WHERE id = ?", id)
-		require.NoError(tt, row.Scan(&actualStatus))
-		require.Equal(tt, api.Invalid, actualStatus)
+		msgs, err = database.GetMessages(api.MessageFilter{Descending: true, Limit: 2})
+		require.NoError(tt, err)
+		require.Equal(tt, []int{all[5].Id, all[4].Id}, []int{msgs[0].Id, msgs[1].Id})
+
+		msgs, err = database.GetMessages(api.MessageFilter{Descending: true, AfterId: all[4].Id, Limit: 2})
+		require.NoError(tt, err)
+		require.Equal(tt, []int{all[3].Id, all[2].Id}, []int{msgs[0].Id, msgs[1].Id})
+	})
+}
+
+func TestDatabase_GetMessage(t *testing.T) {
+	t.Run("it should fetch a message by id or return ErrNotFound", func(tt *testing.T) {
+		testFile := "test_db_fetch_message.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		created, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		msg, err := database.GetMessage(created.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, created, msg)
+
+		_, err = database.GetMessage(created.Id + 1)
+		require.ErrorIs(tt, err, api.ErrNotFound)
+	})
+}
+
+func TestDatabase_GetMessageByProviderMessageId(t *testing.T) {
+	t.Run("it should fetch a message by provider message id or return ErrNotFound", func(tt *testing.T) {
+		testFile := "test_db_fetch_provider_message.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		created, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		_, err = database.GetMessageByProviderMessageId("", "provider-1")
+		require.ErrorIs(tt, err, api.ErrNotFound)
+
+		require.NoError(tt, markAsSent(tt, database, created.Id, time.Now(), "somethirdparty", "provider-1"))
+
+		msg, err := database.GetMessageByProviderMessageId("", "provider-1")
+		require.NoError(tt, err)
+		require.Equal(tt, created.Id, msg.Id)
+		require.Equal(tt, api.Sent, msg.Status)
+		require.Equal(tt, "provider-1", *msg.ProviderMessageId)
+	})
+
+	t.Run("it should return ErrConflict if more than one provider used the id and no provider is given", func(tt *testing.T) {
+		testFile := "test_db_fetch_provider_message_conflict.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		first, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		second, err := database.InsertMessage(api.NewMessage{Content: "Hi!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		require.NoError(tt, markAsSent(tt, database, first.Id, time.Now(), "primary", "42"))
+		require.NoError(tt, markAsSent(tt, database, second.Id, time.Now(), "secondary", "42"))
+
+		_, err = database.GetMessageByProviderMessageId("", "42")
+		require.ErrorIs(tt, err, api.ErrConflict)
+
+		msg, err := database.GetMessageByProviderMessageId("secondary", "42")
+		require.NoError(tt, err)
+		require.Equal(tt, second.Id, msg.Id)
+
+		_, err = database.GetMessageByProviderMessageId("unknown", "42")
+		require.ErrorIs(tt, err, api.ErrNotFound)
+	})
+}
+
+func TestDatabase_InsertMessage(t *testing.T) {
+	t.Run("it should insert an unsent message and return it", func(tt *testing.T) {
+		testFile := "test_db_insert.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+1234567890"}, "")
+		require.NoError(tt, err)
+		require.NotZero(tt, msg.Id)
+		require.Equal(tt, api.Unsent, msg.Status)
+
+		msgs, err := database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 1)
+		require.Equal(tt, msg.Id, msgs[0].Id)
+		require.Equal(tt, "Hello!", msgs[0].Content)
+		require.Equal(tt, "+1234567890", msgs[0].Recipient)
+		require.Equal(tt, 1, msgs[0].Segments)
+		require.Nil(tt, msgs[0].TimeZone)
+	})
+
+	t.Run("it should store the time zone of the recipient", func(tt *testing.T) {
+		testFile := "test_db_insert_time_zone.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		timeZone := "America/New_York"
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+14181234567", TimeZone: &timeZone}, "")
+		require.NoError(tt, err)
+		require.Equal(tt, &timeZone, msg.TimeZone)
+
+		stored, err := database.GetMessage(msg.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, &timeZone, stored.TimeZone)
+	})
+
+	t.Run("it should store the number of segments of long messages", func(tt *testing.T) {
+		testFile := "test_db_insert_segments.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		created, err := database.InsertMessage(api.NewMessage{Content: strings.Repeat("a", 200), Recipient: "+1234567890"}, "")
+		require.NoError(tt, err)
+		require.Equal(tt, 2, created.Segments)
+
+		content := strings.Repeat("ş", 150)
+		updated, err := database.UpdateMessage(created.Id, api.MessageUpdate{Content: &content})
+		require.NoError(tt, err)
+		require.Equal(tt, 3, updated.Segments)
+	})
+}
+
+func TestDatabase_InsertMessage_IdempotencyKey(t *testing.T) {
+	t.Run("it should return the original message when the idempotency key is reused", func(tt *testing.T) {
+		testFile := "test_db_insert_idempotent.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		first, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "dummy-key")
+		require.NoError(tt, err)
+
+		require.NoError(tt, markAsSent(tt, database, first.Id, time.Now(), "somethirdparty", "provider-1"))
+
+		second, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "dummy-key")
+		require.NoError(tt, err)
+		require.Equal(tt, first.Id, second.Id)
+		require.Equal(tt, api.Sent, second.Status)
+
+		msgs, err := database.InsertMessages([]api.NewMessage{
+			{Content: "Hello!", Recipient: "+905551111111"},
+			{Content: "Other", Recipient: "+905551111111"},
+		}, []string{"dummy-key", "other-key"})
+		require.NoError(tt, err)
+		require.Equal(tt, first.Id, msgs[0].Id)
+		require.NotEqual(tt, first.Id, msgs[1].Id)
+
+		// messages without a key are never treated as duplicates
+		a, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		b, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		require.NotEqual(tt, a.Id, b.Id)
+	})
+
+	t.Run("error - should return ErrIdempotencyKeyReused when the key is reused for a different message", func(tt *testing.T) {
+		testFile := "test_db_insert_idempotent_reused.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		_, err = database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "dummy-key")
+		require.NoError(tt, err)
+
+		_, err = database.InsertMessage(api.NewMessage{Content: "Goodbye!", Recipient: "+905551111111"}, "dummy-key")
+		require.ErrorIs(tt, err, api.ErrIdempotencyKeyReused)
+
+		_, err = database.InsertMessages([]api.NewMessage{
+			{Content: "Other", Recipient: "+905551111111"},
+			{Content: "Hello!", Recipient: "+905552222222"},
+		}, []string{"other-key", "dummy-key"})
+		require.ErrorIs(tt, err, api.ErrIdempotencyKeyReused)
+
+		// none of the messages of the failed batch are stored
+		msgs, err := database.InsertMessages([]api.NewMessage{{Content: "Different", Recipient: "+905551111111"}}, []string{"other-key"})
+		require.NoError(tt, err)
+		require.Equal(tt, "Different", msgs[0].Content)
+	})
+}
+
+func TestDatabase_InsertMessages(t *testing.T) {
+	t.Run("it should insert all messages in order", func(tt *testing.T) {
+		testFile := "test_db_insert_many.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msgs, err := database.InsertMessages([]api.NewMessage{
+			{Content: "Hello!", Recipient: "+905551111111"},
+			{Content: "World!", Recipient: "+14181234567"},
+		}, nil)
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 2)
+		require.Less(tt, msgs[0].Id, msgs[1].Id)
+
+		unsent, err := database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Len(tt, unsent, 2)
+		require.Equal(tt, "World!", unsent[1].Content)
 	})
 }
+
+func TestDatabase_MarkMessageAsSent(t *testing.T) {
+	t.Run("it should mark a message as sent and set sent_at", func(tt *testing.T) {
+		testFile := "test_db_mark_sent.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		require.NoError(tt, database.Seed())
+
+		var id int
+		row := database.Conn.QueryRow("SELECT id FROM message WHERE status = ?", api.Unsent)
+		require.NoError(tt, row.Scan(&id))
+
+		expectedSentAt := time.Now()
+		err = database.MarkMessageAsSent(id, claim(tt, database, id), expectedSentAt, "somethirdparty", "provider-1")
+		require.NoError(tt, err)
+
+		var actualStatus api.MessageStatus
+		var actualSentAt, actualProvider, actualProviderMessageId string
+		row = database.Conn.QueryRow("SELECT status, sent_at, provider, provider_message_id FROM message WHERE id = ?", id)
+		require.NoError(tt, row.Scan(&actualStatus, &actualSentAt, &actualProvider, &actualProviderMessageId))
+		require.Equal(tt, "somethirdparty", actualProvider)
+		require.Equal(tt, api.Sent, actualStatus)
+		require.Equal(tt, "provider-1", actualProviderMessageId)
+
+		parsedSentAt, err := time.Parse(time.RFC3339, actualSentAt)
+		require.NoError(tt, err)
+		require.WithinDuration(tt, expectedSentAt.UTC(), parsedSentAt.UTC(), time.Second)
+	})
+}
+
+func TestDatabase_RecordDeliveryReceipt(t *testing.T) {
+	t.Run("it should update the status of the message with the provider message id", func(tt *testing.T) {
+		testFile := "test_db_delivery_receipt.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		first, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		second, err := database.InsertMessage(api.NewMessage{Content: "Hi!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		// not sent yet
+		err = database.RecordDeliveryReceipt("somethirdparty", api.DeliveryReceipt{MessageId: "provider-1", Status: api.Delivered})
+		require.ErrorIs(tt, err, api.ErrNotFound)
+
+		require.NoError(tt, markAsSent(tt, database, first.Id, time.Now(), "somethirdparty", "provider-1"))
+		require.NoError(tt, markAsSent(tt, database, second.Id, time.Now(), "somethirdparty", "provider-2"))
+
+		reason := "handset unreachable"
+		require.NoError(tt, database.RecordDeliveryReceipt("somethirdparty", api.DeliveryReceipt{MessageId: "provider-1", Status: api.Undelivered, Error: &reason}))
+		msg, err := database.GetMessage(first.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Undelivered, msg.Status)
+		require.Equal(tt, reason, *msg.LastError)
+		require.Nil(tt, msg.DeliveredAt)
+
+		// delivered after all, e.g. when the provider retried
+		deliveredAt := time.Date(2025, 5, 31, 10, 0, 5, 0, time.UTC)
+		require.NoError(tt, database.RecordDeliveryReceipt("somethirdparty", api.DeliveryReceipt{MessageId: "provider-1", Status: api.Delivered, DeliveredAt: &deliveredAt}))
+		msg, err = database.GetMessage(first.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Delivered, msg.Status)
+		require.True(tt, deliveredAt.Equal(*msg.DeliveredAt))
+
+		// duplicate and late receipts are ignored
+		require.NoError(tt, database.RecordDeliveryReceipt("somethirdparty", api.DeliveryReceipt{MessageId: "provider-1", Status: api.Delivered}))
+		require.NoError(tt, database.RecordDeliveryReceipt("somethirdparty", api.DeliveryReceipt{MessageId: "provider-1", Status: api.Undelivered}))
+		msg, err = database.GetMessage(first.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Delivered, msg.Status)
+		require.True(tt, deliveredAt.Equal(*msg.DeliveredAt))
+
+		// delivered and undelivered messages are still sent messages
+		sent, err := database.GetSentMessages(api.MessageFilter{Limit: 10})
+		require.NoError(tt, err)
+		require.Len(tt, sent, 2)
+
+		// the other message is not affected
+		msg, err = database.GetMessage(second.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Sent, msg.Status)
+
+		_, err = database.Conn.Exec("UPDATE message SET status = ? WHERE id = ?", api.Sending, second.Id)
+		require.NoError(tt, err)
+		err = database.RecordDeliveryReceipt("somethirdparty", api.DeliveryReceipt{MessageId: "provider-2", Status: api.Delivered})
+		require.ErrorIs(tt, err, api.ErrConflict)
+	})
+
+	t.Run("it should only update the message of the provider that sent the receipt", func(tt *testing.T) {
+		testFile := "test_db_delivery_receipt_provider.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		first, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		second, err := database.InsertMessage(api.NewMessage{Content: "Hi!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		// both providers happen to use the same id
+		require.NoError(tt, markAsSent(tt, database, first.Id, time.Now(), "primary", "42"))
+		require.NoError(tt, markAsSent(tt, database, second.Id, time.Now(), "secondary", "42"))
+
+		require.NoError(tt, database.RecordDeliveryReceipt("secondary", api.DeliveryReceipt{MessageId: "42", Status: api.Delivered}))
+
+		msg, err := database.GetMessage(first.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Sent, msg.Status)
+		msg, err = database.GetMessage(second.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Delivered, msg.Status)
+
+		err = database.RecordDeliveryReceipt("unknown", api.DeliveryReceipt{MessageId: "42", Status: api.Delivered})
+		require.ErrorIs(tt, err, api.ErrNotFound)
+
+		// a provider cannot use the same id twice
+		third, err := database.InsertMessage(api.NewMessage{Content: "Hey!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		require.Error(tt, markAsSent(tt, database, third.Id, time.Now(), "primary", "42"))
+	})
+}
+
+func TestDatabase_MarkMessageAsInvalid(t *testing.T) {
+	t.Run("it should mark a message as invalid", func(tt *testing.T) {
+		testFile := "test_db_mark_invalid.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		require.NoError(tt, database.Seed())
+
+		var id int
+		row := database.Conn.QueryRow("SELECT id FROM message WHERE status = ?", api.Unsent)
+		require.NoError(tt, row.Scan(&id))
+		err = database.MarkMessageAsInvalid(id, claim(tt, database, id), api.ErrInvalidRecipient.Error())
+		require.NoError(tt, err)
+
+		msg, err := database.GetMessage(id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Invalid, msg.Status)
+		require.NotNil(tt, msg.LastError)
+		require.Equal(tt, api.ErrInvalidRecipient.Error(), *msg.LastError)
+	})
+
+	t.Run("it should not mark a message that is not being sent", func(tt *testing.T) {
+		testFile := "test_db_mark_invalid_cancelled.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		_, err = database.CancelMessage(msg.Id)
+		require.NoError(tt, err)
+
+		require.NoError(tt, database.MarkMessageAsInvalid(msg.Id, time.Now(), api.ErrInvalidRecipient.Error()))
+
+		msg, err = database.GetMessage(msg.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Cancelled, msg.Status)
+		require.Nil(tt, msg.LastError)
+	})
+}
+
+func TestDatabase_RecordFailedAttempt(t *testing.T) {
+	t.Run("it should count the attempt, postpone the message and keep the provider to retry it with", func(tt *testing.T) {
+		testFile := "test_db_failed_attempt.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		require.NoError(tt, database.RecordFailedAttempt(msg.Id, claim(tt, database, msg.Id), "dummy error", time.Now().Add(time.Hour), "primary"))
+
+		msgs, err := database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Empty(tt, msgs)
+
+		require.NoError(tt, database.RecordFailedAttempt(msg.Id, claim(tt, database, msg.Id), "another error", time.Now().Add(-time.Second), ""))
+
+		msgs, err = database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 1)
+		require.Equal(tt, 2, msgs[0].Attempts)
+		require.Equal(tt, "another error", *msgs[0].LastError)
+		require.Equal(tt, "primary", *msgs[0].Provider, "the provider the outcome is unknown with should be kept")
+	})
+}
+
+func TestDatabase_MarkMessageAsFailed(t *testing.T) {
+	t.Run("it should mark a message as failed and keep the last error", func(tt *testing.T) {
+		testFile := "test_db_mark_failed.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		require.NoError(tt, database.MarkMessageAsFailed(msg.Id, claim(tt, database, msg.Id), "dummy error"))
+
+		var actualStatus api.MessageStatus
+		var attempts int
+		var lastError string
+		row := database.Conn.QueryRow("SELECT status, attempts, last_error FROM message WHERE id = ?", msg.Id)
+		require.NoError(tt, row.Scan(&actualStatus, &attempts, &lastError))
+		require.Equal(tt, api.Failed, actualStatus)
+		require.Equal(tt, 1, attempts)
+		require.Equal(tt, "dummy error", lastError)
+	})
+}
+
+func TestDatabase_GetSentTimes(t *testing.T) {
+	t.Run("it should return the sent times of the recipient in the window, oldest first", func(tt *testing.T) {
+		testFile := "test_db_sent_times.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		now := time.Now().UTC().Truncate(time.Second)
+		sentAts := []time.Time{now.Add(-time.Minute), now.Add(-2 * time.Hour), now.Add(-25 * time.Hour)}
+		for _, sentAt := range sentAts {
+			msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+			require.NoError(tt, err)
+			require.NoError(tt, markAsSent(tt, database, msg.Id, sentAt, "somethirdparty", ""))
+		}
+
+		// unsent messages and messages to other recipients do not count
+		_, err = database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		other, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905552222222"}, "")
+		require.NoError(tt, err)
+		require.NoError(tt, markAsSent(tt, database, other.Id, now, "somethirdparty", ""))
+
+		times, err := database.GetSentTimes("+905551111111", now.Add(-24*time.Hour))
+		require.NoError(tt, err)
+		require.Len(tt, times, 2)
+		require.True(tt, sentAts[1].Equal(times[0]))
+		require.True(tt, sentAts[0].Equal(times[1]))
+	})
+}
+
+func TestDatabase_DeferMessage(t *testing.T) {
+	t.Run("it should put a message back in the queue later without counting an attempt", func(tt *testing.T) {
+		testFile := "test_db_defer.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		require.NoError(tt, database.DeferMessage(msg.Id, claim(tt, database, msg.Id), time.Now().Add(time.Hour)))
+
+		msgs, err := database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Empty(tt, msgs)
+
+		require.NoError(tt, database.DeferMessage(msg.Id, claim(tt, database, msg.Id), time.Now().Add(-time.Second)))
+
+		msgs, err = database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 1)
+		require.Equal(tt, 0, msgs[0].Attempts)
+		require.Nil(tt, msgs[0].LastError)
+	})
+}
+
+func TestDatabase_MarkMessageAsSending(t *testing.T) {
+	t.Run("it should claim an unsent message only once", func(tt *testing.T) {
+		testFile := "test_db_mark_sending.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		claimedMsg, _, claimed, err := database.MarkMessageAsSending(msg.Id)
+		require.NoError(tt, err)
+		require.True(tt, claimed)
+		require.Equal(tt, msg.Id, claimedMsg.Id)
+		require.Equal(tt, api.Sending, claimedMsg.Status)
+
+		_, _, claimed, err = database.MarkMessageAsSending(msg.Id)
+		require.NoError(tt, err)
+		require.False(tt, claimed)
+
+		msgs, err := database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Empty(tt, msgs)
+	})
+
+	t.Run("it should return the message as updated after it was fetched", func(tt *testing.T) {
+		testFile := "test_db_mark_sending_updated.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		_, err = database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		fetched, err := database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Len(tt, fetched, 1)
+
+		content := "Hello again!"
+		recipient := "+905552222222"
+		_, err = database.UpdateMessage(fetched[0].Id, api.MessageUpdate{Content: &content, Recipient: &recipient})
+		require.NoError(tt, err)
+
+		claimedMsg, _, claimed, err := database.MarkMessageAsSending(fetched[0].Id)
+		require.NoError(tt, err)
+		require.True(tt, claimed)
+		require.Equal(tt, content, claimedMsg.Content)
+		require.Equal(tt, recipient, claimedMsg.Recipient)
+	})
+}
+
+func TestDatabase_ResetSendingMessages(t *testing.T) {
+	t.Run("it should put messages stuck in sending back to unsent and count the attempt", func(tt *testing.T) {
+		testFile := "test_db_reset_sending.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		_, _, _, err = database.MarkMessageAsSending(msg.Id)
+		require.NoError(tt, err)
+
+		count, err := database.ResetSendingMessages(time.Now().Add(-time.Minute), 3)
+		require.NoError(tt, err)
+		require.Zero(tt, count, "messages claimed recently should be left alone")
+
+		count, err = database.ResetSendingMessages(time.Now().Add(time.Second), 3)
+		require.NoError(tt, err)
+		require.Equal(tt, 1, count)
+
+		msgs, err := database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 1)
+		require.Equal(tt, 1, msgs[0].Attempts)
+		require.NotNil(tt, msgs[0].LastError)
+	})
+
+	t.Run("it should mark messages interrupted too many times as failed", func(tt *testing.T) {
+		testFile := "test_db_reset_sending_failed.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		for range 2 {
+			_, _, _, err = database.MarkMessageAsSending(msg.Id)
+			require.NoError(tt, err)
+			_, err = database.ResetSendingMessages(time.Now().Add(time.Second), 2)
+			require.NoError(tt, err)
+		}
+
+		stored, err := database.GetMessage(msg.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Failed, stored.Status)
+		require.Equal(tt, 2, stored.Attempts)
+	})
+
+	t.Run("it should not record the outcome of a send whose message was reset", func(tt *testing.T) {
+		testFile := "test_db_reset_sending_late.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		claimedAt := claim(tt, database, msg.Id)
+
+		_, err = database.ResetSendingMessages(time.Now().Add(time.Second), 0)
+		require.NoError(tt, err)
+		cancelled, err := database.CancelMessage(msg.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Cancelled, cancelled.Status)
+
+		// the send that was still running finishes after the message was cancelled
+		require.NoError(tt, database.RecordFailedAttempt(msg.Id, claimedAt, "network error", time.Now(), ""))
+		require.NoError(tt, database.MarkMessageAsFailed(msg.Id, claimedAt, "rejected"))
+		require.NoError(tt, database.MarkMessageAsSent(msg.Id, claimedAt, time.Now(), "somethirdparty", "provider-1"))
+
+		stored, err := database.GetMessage(msg.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Cancelled, stored.Status)
+		require.Equal(tt, 1, stored.Attempts)
+	})
+
+	t.Run("it should only record the outcome of the latest claim", func(tt *testing.T) {
+		testFile := "test_db_reset_sending_reclaimed.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+		staleClaim := claim(tt, database, msg.Id)
+
+		_, err = database.ResetSendingMessages(time.Now().Add(time.Second), 0)
+		require.NoError(tt, err)
+		latestClaim := claim(tt, database, msg.Id)
+
+		require.NoError(tt, database.RecordFailedAttempt(msg.Id, staleClaim, "network error", time.Now(), ""))
+		stored, err := database.GetMessage(msg.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Sending, stored.Status)
+
+		require.NoError(tt, database.MarkMessageAsSent(msg.Id, latestClaim, time.Now(), "somethirdparty", "provider-1"))
+		stored, err = database.GetMessage(msg.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Sent, stored.Status)
+	})
+}
+
+func TestDatabase_UpdateMessage(t *testing.T) {
+	t.Run("it should update an unsent message and refuse to update it once it is picked up", func(tt *testing.T) {
+		testFile := "test_db_update.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		created, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		content := "Hello again!"
+		priority := 5
+		timeZone := "Europe/Istanbul"
+		updated, err := database.UpdateMessage(created.Id, api.MessageUpdate{Content: &content, Priority: &priority, TimeZone: &timeZone})
+		require.NoError(tt, err)
+		require.Equal(tt, content, updated.Content)
+		require.Equal(tt, priority, updated.Priority)
+		require.Equal(tt, &timeZone, updated.TimeZone)
+		require.Equal(tt, created.Recipient, updated.Recipient)
+
+		_, _, _, err = database.MarkMessageAsSending(created.Id)
+		require.NoError(tt, err)
+
+		_, err = database.UpdateMessage(created.Id, api.MessageUpdate{Content: &content})
+		require.ErrorIs(tt, err, api.ErrConflict)
+
+		_, err = database.UpdateMessage(created.Id+1, api.MessageUpdate{Content: &content})
+		require.ErrorIs(tt, err, api.ErrNotFound)
+	})
+}
+
+func TestDatabase_CancelMessage(t *testing.T) {
+	t.Run("it should cancel an unsent message only once", func(tt *testing.T) {
+		testFile := "test_db_cancel.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		created, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		cancelled, err := database.CancelMessage(created.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Cancelled, cancelled.Status)
+
+		_, err = database.CancelMessage(created.Id)
+		require.ErrorIs(tt, err, api.ErrConflict)
+
+		_, err = database.CancelMessage(created.Id + 1)
+		require.ErrorIs(tt, err, api.ErrNotFound)
+
+		msgs, err := database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Empty(tt, msgs)
+	})
+}
+
+// claim marks the message as sending and returns the time it was claimed at
+func claim(t *testing.T, database *db.Database, id int) time.Time {
+	t.Helper()
+	_, claimedAt, claimed, err := database.MarkMessageAsSending(id)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	return claimedAt
+}
+
+// markAsSent claims the message and marks it as sent
+func markAsSent(t *testing.T, database *db.Database, id int, sentAt time.Time, provider string, providerMessageId string) error {
+	t.Helper()
+	return database.MarkMessageAsSent(id, claim(t, database, id), sentAt, provider, providerMessageId)
+}
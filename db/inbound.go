@@ -0,0 +1,166 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/taylankasap/message-sender/api"
+)
+
+// inboundMessageTable defines the table of messages received from recipients. Provider message
+// ids are only unique per provider.
+const inboundMessageTable = `(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	provider TEXT,
+	provider_message_id TEXT,
+	sender TEXT NOT NULL,
+	recipient TEXT,
+	content TEXT NOT NULL,
+	keyword TEXT,
+	received_at DATETIME NOT NULL,
+	UNIQUE (provider, provider_message_id)
+)`
+
+// createInboundMessageTable creates the table of messages received from recipients. A table
+// created before messages were stored with their provider, whose provider message ids were unique
+// across providers, is rebuilt, since SQLite cannot drop the constraint.
+func createInboundMessageTable(db *sql.DB) error {
+	var columns int
+	var hasProvider bool
+	err := db.QueryRow("SELECT COUNT(*), COALESCE(SUM(name = 'provider'), 0) > 0 FROM pragma_table_info('inbound_message')").Scan(&columns, &hasProvider)
+	if err != nil {
+		return err
+	}
+	if columns == 0 || hasProvider {
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS inbound_message " + inboundMessageTable)
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, query := range []string{
+		"CREATE TABLE inbound_message_new " + inboundMessageTable,
+		`INSERT INTO inbound_message_new (id, provider_message_id, sender, recipient, content, keyword, received_at)
+			SELECT id, provider_message_id, sender, recipient, content, keyword, received_at FROM inbound_message`,
+		"DROP TABLE inbound_message",
+		"ALTER TABLE inbound_message_new RENAME TO inbound_message",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// inboundMessageColumns is the list of columns scanned by scanInboundMessage
+const inboundMessageColumns = "id, provider, provider_message_id, sender, recipient, content, keyword, received_at"
+
+// scanInboundMessage scans a row selected with inboundMessageColumns
+func scanInboundMessage(row interface{ Scan(dest ...any) error }) (api.InboundMessage, error) {
+	var m api.InboundMessage
+	err := row.Scan(&m.Id, &m.Provider, &m.ProviderMessageId, &m.From, &m.To, &m.Content, &m.Keyword, &m.ReceivedAt)
+	return m, err
+}
+
+// InsertInboundMessage stores a message the provider received from a recipient and, in the same
+// transaction, adds the sender to the suppression list for a stop keyword or removes them from it
+// for a start keyword. A message with the same provider and provider message id as a stored one is
+// not stored or applied again, the stored one is returned instead.
+func (d *Database) InsertInboundMessage(provider string, m api.NewInboundMessage, keyword *api.InboundKeyword) (api.InboundMessage, error) {
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return api.InboundMessage{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if m.MessageId != nil {
+		existing, err := scanInboundMessage(tx.QueryRow("SELECT "+inboundMessageColumns+" FROM inbound_message WHERE provider = ? AND provider_message_id = ?", provider, *m.MessageId))
+		if err == nil {
+			return existing, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return api.InboundMessage{}, err
+		}
+	}
+
+	res, err := tx.Exec(
+		"INSERT INTO inbound_message (provider, provider_message_id, sender, recipient, content, keyword, received_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		provider, m.MessageId, m.From, m.To, m.Content, keyword, formatTime(m.ReceivedAt),
+	)
+	if err != nil {
+		return api.InboundMessage{}, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return api.InboundMessage{}, err
+	}
+
+	if keyword != nil {
+		switch *keyword {
+		case api.Stop:
+			reason := fmt.Sprintf("replied %q", m.Content)
+			err = insertSuppression(tx, api.NewSuppression{Recipient: m.From, Reason: &reason})
+		case api.Start:
+			_, err = tx.Exec("DELETE FROM suppression WHERE recipient = ?", m.From)
+		}
+		if err != nil {
+			return api.InboundMessage{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return api.InboundMessage{}, err
+	}
+
+	return api.InboundMessage{
+		Id:                int(id),
+		Provider:          &provider,
+		ProviderMessageId: m.MessageId,
+		From:              m.From,
+		To:                m.To,
+		Content:           m.Content,
+		Keyword:           keyword,
+		ReceivedAt:        *m.ReceivedAt,
+	}, nil
+}
+
+// GetInboundMessages fetches the messages received from recipients, oldest first
+func (d *Database) GetInboundMessages(filter api.InboundMessageFilter) ([]api.InboundMessage, error) {
+	query := "SELECT " + inboundMessageColumns + " FROM inbound_message WHERE 1 = 1"
+	var args []any
+
+	if filter.From != "" {
+		query += " AND sender = ?"
+		args = append(args, filter.From)
+	}
+	if filter.AfterId != 0 {
+		query += " AND id > ?"
+		args = append(args, filter.AfterId)
+	}
+
+	query += " ORDER BY id ASC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := d.Conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []api.InboundMessage{}
+	for rows.Next() {
+		m, err := scanInboundMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
@@ -0,0 +1,160 @@
+package db_test
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/taylankasap/message-sender/api"
+
+	"github.com/stretchr/testify/require"
+	"github.com/taylankasap/message-sender/db"
+)
+
+func TestDatabase_InsertInboundMessage(t *testing.T) {
+	t.Run("it should store the message and update the suppression list for keywords", func(tt *testing.T) {
+		testFile := "test_db_inbound.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		receivedAt := time.Date(2025, 5, 31, 10, 0, 0, 0, time.UTC)
+		stop := api.Stop
+		messageId := "dummy-message-id"
+		stored, err := database.InsertInboundMessage("somethirdparty", api.NewInboundMessage{MessageId: &messageId, From: "+905551111111", Content: "IPTAL", ReceivedAt: &receivedAt}, &stop)
+		require.NoError(tt, err)
+		require.NotZero(tt, stored.Id)
+
+		suppression, err := database.GetSuppression("+905551111111")
+		require.NoError(tt, err)
+		require.Equal(tt, `replied "IPTAL"`, *suppression.Reason)
+
+		// the provider sending the same message again does not store it twice
+		again, err := database.InsertInboundMessage("somethirdparty", api.NewInboundMessage{MessageId: &messageId, From: "+905551111111", Content: "IPTAL", ReceivedAt: &receivedAt}, &stop)
+		require.NoError(tt, err)
+		require.Equal(tt, stored, again)
+
+		// another provider may use the same id for a different message
+		other, err := database.InsertInboundMessage("otherprovider", api.NewInboundMessage{MessageId: &messageId, From: "+905553333333", Content: "STOP", ReceivedAt: &receivedAt}, &stop)
+		require.NoError(tt, err)
+		require.NotEqual(tt, stored.Id, other.Id)
+		require.Equal(tt, "otherprovider", *other.Provider)
+
+		suppressed, err := database.IsSuppressed("+905553333333")
+		require.NoError(tt, err)
+		require.True(tt, suppressed)
+
+		start := api.Start
+		_, err = database.InsertInboundMessage("somethirdparty", api.NewInboundMessage{From: "+905551111111", Content: "START", ReceivedAt: &receivedAt}, &start)
+		require.NoError(tt, err)
+
+		suppressed, err = database.IsSuppressed("+905551111111")
+		require.NoError(tt, err)
+		require.False(tt, suppressed)
+
+		_, err = database.InsertInboundMessage("somethirdparty", api.NewInboundMessage{From: "+905552222222", Content: "Thanks!", ReceivedAt: &receivedAt}, nil)
+		require.NoError(tt, err)
+
+		suppressed, err = database.IsSuppressed("+905552222222")
+		require.NoError(tt, err)
+		require.False(tt, suppressed)
+
+		msgs, err := database.GetInboundMessages(api.InboundMessageFilter{Limit: 10})
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 4)
+		require.Equal(tt, stored, msgs[0])
+		require.Equal(tt, api.Start, *msgs[2].Keyword)
+		require.Nil(tt, msgs[3].Keyword)
+	})
+}
+
+func TestDatabase_InsertInboundMessage_Migration(t *testing.T) {
+	t.Run("it should keep the messages of a table where provider message ids were unique across providers", func(tt *testing.T) {
+		testFile := "test_db_inbound_migrate.sqlite3"
+		_ = os.Remove(testFile)
+
+		conn, err := sql.Open("sqlite3", testFile)
+		require.NoError(tt, err)
+		_, err = conn.Exec(`CREATE TABLE inbound_message (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			provider_message_id TEXT UNIQUE,
+			sender TEXT NOT NULL,
+			recipient TEXT,
+			content TEXT NOT NULL,
+			keyword TEXT,
+			received_at DATETIME NOT NULL
+		)`)
+		require.NoError(tt, err)
+		_, err = conn.Exec("INSERT INTO inbound_message (provider_message_id, sender, content, received_at) VALUES ('42', '+905551111111', 'Hi', '2025-05-31T10:00:00Z')")
+		require.NoError(tt, err)
+		require.NoError(tt, conn.Close())
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		messageId := "42"
+		receivedAt := time.Date(2025, 5, 31, 11, 0, 0, 0, time.UTC)
+		_, err = database.InsertInboundMessage("somethirdparty", api.NewInboundMessage{MessageId: &messageId, From: "+905552222222", Content: "Hello", ReceivedAt: &receivedAt}, nil)
+		require.NoError(tt, err)
+
+		msgs, err := database.GetInboundMessages(api.InboundMessageFilter{Limit: 10})
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 2)
+		require.Equal(tt, "Hi", msgs[0].Content)
+		require.Nil(tt, msgs[0].Provider)
+		require.Equal(tt, "somethirdparty", *msgs[1].Provider)
+
+		// opening it again should not rebuild the table
+		again, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		again.Conn.Close()
+	})
+}
+
+func TestDatabase_GetInboundMessages(t *testing.T) {
+	t.Run("it should filter by sender and paginate", func(tt *testing.T) {
+		testFile := "test_db_get_inbound.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		receivedAt := time.Now()
+		var ids []int
+		for _, from := range []string{"+905551111111", "+905552222222", "+905551111111"} {
+			msg, err := database.InsertInboundMessage("somethirdparty", api.NewInboundMessage{From: from, Content: "Hi", ReceivedAt: &receivedAt}, nil)
+			require.NoError(tt, err)
+			ids = append(ids, msg.Id)
+		}
+
+		msgs, err := database.GetInboundMessages(api.InboundMessageFilter{From: "+905551111111", Limit: 10})
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 2)
+		require.Equal(tt, ids[0], msgs[0].Id)
+		require.Equal(tt, ids[2], msgs[1].Id)
+
+		msgs, err = database.GetInboundMessages(api.InboundMessageFilter{AfterId: ids[0], Limit: 1})
+		require.NoError(tt, err)
+		require.Len(tt, msgs, 1)
+		require.Equal(tt, ids[1], msgs[0].Id)
+	})
+}
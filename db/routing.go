@@ -0,0 +1,51 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/taylankasap/message-sender/api"
+)
+
+// createRoutingTable creates the table the routing table set at runtime is stored in, as a
+// single row
+func createRoutingTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS routing (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		routes TEXT NOT NULL
+	)`)
+	return err
+}
+
+// GetRoutingTable fetches the stored routing table, or returns api.ErrNotFound if none was stored
+func (d *Database) GetRoutingTable() (api.RoutingTable, error) {
+	var routes string
+	err := d.Conn.QueryRow("SELECT routes FROM routing WHERE id = 1").Scan(&routes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.RoutingTable{}, api.ErrNotFound
+	}
+	if err != nil {
+		return api.RoutingTable{}, err
+	}
+
+	var table api.RoutingTable
+	if err := json.Unmarshal([]byte(routes), &table); err != nil {
+		return api.RoutingTable{}, err
+	}
+	return table, nil
+}
+
+// SaveRoutingTable stores the routing table, replacing the stored one
+func (d *Database) SaveRoutingTable(table api.RoutingTable) error {
+	routes, err := json.Marshal(table)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.Conn.Exec(
+		"INSERT INTO routing (id, routes) VALUES (1, ?) ON CONFLICT (id) DO UPDATE SET routes = excluded.routes",
+		string(routes),
+	)
+	return err
+}
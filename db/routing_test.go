@@ -0,0 +1,42 @@
+package db_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/taylankasap/message-sender/api"
+
+	"github.com/stretchr/testify/require"
+	"github.com/taylankasap/message-sender/db"
+)
+
+func TestDatabase_RoutingTable(t *testing.T) {
+	t.Run("it should store the routing table and replace it", func(tt *testing.T) {
+		testFile := "test_db_routing.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		_, err = database.GetRoutingTable()
+		require.ErrorIs(tt, err, api.ErrNotFound)
+
+		first := api.RoutingTable{DefaultProviders: []string{"global"}, Routes: []api.Route{{Prefix: "+90", Providers: []string{"turkey", "global"}}}}
+		require.NoError(tt, database.SaveRoutingTable(first))
+		table, err := database.GetRoutingTable()
+		require.NoError(tt, err)
+		require.Equal(tt, first, table)
+
+		second := api.RoutingTable{DefaultProviders: []string{}, Routes: []api.Route{}}
+		require.NoError(tt, database.SaveRoutingTable(second))
+		table, err = database.GetRoutingTable()
+		require.NoError(tt, err)
+		require.Equal(tt, second, table)
+	})
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/taylankasap/message-sender/api"
+)
+
+// createSuppressionTable creates the table of recipients who opted out of receiving messages
+func createSuppressionTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS suppression (
+		recipient TEXT PRIMARY KEY,
+		reason TEXT,
+		created_at DATETIME NOT NULL
+	)`)
+	return err
+}
+
+// GetSuppressions fetches all suppressed recipients from the database, oldest first
+func (d *Database) GetSuppressions() ([]api.Suppression, error) {
+	rows, err := d.Conn.Query("SELECT recipient, reason, created_at FROM suppression ORDER BY datetime(created_at) ASC, recipient ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppressions := []api.Suppression{}
+	for rows.Next() {
+		var s api.Suppression
+		if err := rows.Scan(&s.Recipient, &s.Reason, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		suppressions = append(suppressions, s)
+	}
+
+	return suppressions, rows.Err()
+}
+
+// GetSuppression fetches the suppression of a recipient, or returns api.ErrNotFound if the
+// recipient is not suppressed
+func (d *Database) GetSuppression(recipient string) (api.Suppression, error) {
+	var s api.Suppression
+	err := d.Conn.QueryRow("SELECT recipient, reason, created_at FROM suppression WHERE recipient = ?", recipient).Scan(&s.Recipient, &s.Reason, &s.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.Suppression{}, api.ErrNotFound
+	}
+	return s, err
+}
+
+// InsertSuppression suppresses a recipient and returns the suppression. If the recipient is
+// already suppressed, the existing suppression is kept and returned.
+func (d *Database) InsertSuppression(s api.NewSuppression) (api.Suppression, error) {
+	if err := insertSuppression(d.Conn, s); err != nil {
+		return api.Suppression{}, err
+	}
+
+	return d.GetSuppression(s.Recipient)
+}
+
+// insertSuppression suppresses a recipient unless they are already suppressed, using either a
+// connection or a transaction
+func insertSuppression(q querier, s api.NewSuppression) error {
+	now := time.Now()
+	_, err := q.Exec(
+		"INSERT INTO suppression (recipient, reason, created_at) VALUES (?, ?, ?) ON CONFLICT (recipient) DO NOTHING",
+		s.Recipient, s.Reason, formatTime(&now),
+	)
+	return err
+}
+
+// DeleteSuppression removes a recipient from the suppression list, or returns api.ErrNotFound if
+// the recipient is not suppressed
+func (d *Database) DeleteSuppression(recipient string) error {
+	res, err := d.Conn.Exec("DELETE FROM suppression WHERE recipient = ?", recipient)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return api.ErrNotFound
+	}
+
+	return nil
+}
+
+// IsSuppressed reports whether the recipient opted out of receiving messages
+func (d *Database) IsSuppressed(recipient string) (bool, error) {
+	var suppressed bool
+	err := d.Conn.QueryRow("SELECT EXISTS (SELECT 1 FROM suppression WHERE recipient = ?)", recipient).Scan(&suppressed)
+	return suppressed, err
+}
+
+// MarkMessageAsSuppressed updates the status of a message that is being sent to suppressed, so it
+// is never sent to the recipient who opted out
+func (d *Database) MarkMessageAsSuppressed(id int, claimedAt time.Time) error {
+	_, err := d.Conn.Exec(
+		"UPDATE message SET status = ? WHERE id = ? AND status = ? AND claimed_at = ?",
+		api.Suppressed, id, api.Sending, formatClaim(claimedAt),
+	)
+	return err
+}
@@ -0,0 +1,94 @@
+package db_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/taylankasap/message-sender/api"
+
+	"github.com/stretchr/testify/require"
+	"github.com/taylankasap/message-sender/db"
+)
+
+func TestDatabase_Suppressions(t *testing.T) {
+	t.Run("it should suppress, fetch and remove recipients", func(tt *testing.T) {
+		testFile := "test_db_suppressions.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		reason := "unsubscribed on the website"
+		created, err := database.InsertSuppression(api.NewSuppression{Recipient: "+905551111111", Reason: &reason})
+		require.NoError(tt, err)
+		require.Equal(tt, "+905551111111", created.Recipient)
+		require.Equal(tt, &reason, created.Reason)
+		require.False(tt, created.CreatedAt.IsZero())
+
+		// suppressing again keeps the original suppression
+		again, err := database.InsertSuppression(api.NewSuppression{Recipient: "+905551111111"})
+		require.NoError(tt, err)
+		require.Equal(tt, created, again)
+
+		fetched, err := database.GetSuppression("+905551111111")
+		require.NoError(tt, err)
+		require.Equal(tt, created, fetched)
+
+		suppressions, err := database.GetSuppressions()
+		require.NoError(tt, err)
+		require.Equal(tt, []api.Suppression{created}, suppressions)
+
+		suppressed, err := database.IsSuppressed("+905551111111")
+		require.NoError(tt, err)
+		require.True(tt, suppressed)
+
+		suppressed, err = database.IsSuppressed("+905552222222")
+		require.NoError(tt, err)
+		require.False(tt, suppressed)
+
+		require.NoError(tt, database.DeleteSuppression("+905551111111"))
+		require.ErrorIs(tt, database.DeleteSuppression("+905551111111"), api.ErrNotFound)
+
+		_, err = database.GetSuppression("+905551111111")
+		require.ErrorIs(tt, err, api.ErrNotFound)
+
+		suppressed, err = database.IsSuppressed("+905551111111")
+		require.NoError(tt, err)
+		require.False(tt, suppressed)
+	})
+}
+
+func TestDatabase_MarkMessageAsSuppressed(t *testing.T) {
+	t.Run("it should mark a message that is being sent as suppressed", func(tt *testing.T) {
+		testFile := "test_db_mark_suppressed.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		msg, err := database.InsertMessage(api.NewMessage{Content: "Hello!", Recipient: "+905551111111"}, "")
+		require.NoError(tt, err)
+
+		require.NoError(tt, database.MarkMessageAsSuppressed(msg.Id, claim(tt, database, msg.Id)))
+
+		stored, err := database.GetMessage(msg.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, api.Suppressed, stored.Status)
+
+		msgs, err := database.GetUnsentMessages(10)
+		require.NoError(tt, err)
+		require.Empty(tt, msgs)
+	})
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/taylankasap/message-sender/api"
+)
+
+// createTemplateTable creates the table of message templates
+func createTemplateTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS template (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		body TEXT NOT NULL
+	)`)
+	return err
+}
+
+// GetTemplates fetches all templates from the database
+func (d *Database) GetTemplates() ([]api.Template, error) {
+	rows, err := d.Conn.Query("SELECT id, name, body FROM template ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []api.Template{}
+	for rows.Next() {
+		var t api.Template
+		if err := rows.Scan(&t.Id, &t.Name, &t.Body); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	return templates, nil
+}
+
+// GetTemplate fetches a single template from the database, or returns api.ErrNotFound
+func (d *Database) GetTemplate(id int) (api.Template, error) {
+	var t api.Template
+	err := d.Conn.QueryRow("SELECT id, name, body FROM template WHERE id = ?", id).Scan(&t.Id, &t.Name, &t.Body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.Template{}, api.ErrNotFound
+	}
+	return t, err
+}
+
+// InsertTemplate stores a new template and returns it, or returns api.ErrConflict if the name is taken
+func (d *Database) InsertTemplate(t api.NewTemplate) (api.Template, error) {
+	res, err := d.Conn.Exec("INSERT INTO template (name, body) VALUES (?, ?)", t.Name, t.Body)
+	if err != nil {
+		return api.Template{}, templateError(err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return api.Template{}, err
+	}
+
+	return api.Template{Id: int(id), Name: t.Name, Body: t.Body}, nil
+}
+
+// UpdateTemplate replaces a template and returns it, or returns api.ErrNotFound if it does not
+// exist and api.ErrConflict if the new name is taken
+func (d *Database) UpdateTemplate(id int, t api.NewTemplate) (api.Template, error) {
+	res, err := d.Conn.Exec("UPDATE template SET name = ?, body = ? WHERE id = ?", t.Name, t.Body, id)
+	if err != nil {
+		return api.Template{}, templateError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return api.Template{}, err
+	}
+	if affected == 0 {
+		return api.Template{}, api.ErrNotFound
+	}
+
+	return api.Template{Id: id, Name: t.Name, Body: t.Body}, nil
+}
+
+// DeleteTemplate deletes a template, or returns api.ErrNotFound if it does not exist
+func (d *Database) DeleteTemplate(id int) error {
+	res, err := d.Conn.Exec("DELETE FROM template WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return api.ErrNotFound
+	}
+
+	return nil
+}
+
+// templateError turns a violation of the unique template name into api.ErrConflict
+func templateError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return api.ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package db_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/taylankasap/message-sender/api"
+
+	"github.com/stretchr/testify/require"
+	"github.com/taylankasap/message-sender/db"
+)
+
+func TestDatabase_Templates(t *testing.T) {
+	t.Run("it should create, fetch, update and delete templates", func(tt *testing.T) {
+		testFile := "test_db_templates.sqlite3"
+		_ = os.Remove(testFile)
+
+		database, err := db.New(&db.Config{Filename: testFile})
+		require.NoError(tt, err)
+		require.NotNil(tt, database.Conn)
+
+		defer func() {
+			database.Conn.Close()
+			_ = os.Remove(testFile)
+		}()
+
+		created, err := database.InsertTemplate(api.NewTemplate{Name: "otp", Body: "Your code is {{.code}}"})
+		require.NoError(tt, err)
+		require.NotZero(tt, created.Id)
+
+		_, err = database.InsertTemplate(api.NewTemplate{Name: "otp", Body: "Another body"})
+		require.ErrorIs(tt, err, api.ErrConflict)
+
+		fetched, err := database.GetTemplate(created.Id)
+		require.NoError(tt, err)
+		require.Equal(tt, created, fetched)
+
+		updated, err := database.UpdateTemplate(created.Id, api.NewTemplate{Name: "otp", Body: "Code: {{.code}}"})
+		require.NoError(tt, err)
+		require.Equal(tt, "Code: {{.code}}", updated.Body)
+
+		templates, err := database.GetTemplates()
+		require.NoError(tt, err)
+		require.Equal(tt, []api.Template{updated}, templates)
+
+		require.NoError(tt, database.DeleteTemplate(created.Id))
+		require.ErrorIs(tt, database.DeleteTemplate(created.Id), api.ErrNotFound)
+
+		_, err = database.GetTemplate(created.Id)
+		require.ErrorIs(tt, err, api.ErrNotFound)
+
+		_, err = database.UpdateTemplate(created.Id, api.NewTemplate{Name: "otp", Body: "Code: {{.code}}"})
+		require.ErrorIs(tt, err, api.ErrNotFound)
+	})
+}
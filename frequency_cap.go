@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// FrequencyCap limits the number of messages a recipient receives in a rolling window
+type FrequencyCap struct {
+	Limit  int           // Maximum number of messages sent to the same recipient in the window
+	Window time.Duration // e.g. 24 hours
+}
+
+// nextAllowedTime returns the time a message can be sent to the recipient without exceeding
+// any frequency cap, or the zero time if it can be sent now
+func (d *MessageDispatcher) nextAllowedTime(recipient string, now time.Time) (time.Time, error) {
+	var longest time.Duration
+	for _, c := range d.FrequencyCaps {
+		longest = max(longest, c.Window)
+	}
+
+	sent, err := d.sentTimes(recipient, now.Add(-longest))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var allowedAt time.Time
+	for _, c := range d.FrequencyCaps {
+		if c.Limit <= 0 {
+			continue
+		}
+
+		inWindow := 0
+		for _, sentAt := range sent {
+			if sentAt.After(now.Add(-c.Window)) {
+				inWindow++
+			}
+		}
+		if inWindow < c.Limit {
+			continue
+		}
+
+		// sent is ordered oldest first, so one more message can be sent once the
+		// limit-th newest message leaves the window
+		leavesAt := sent[len(sent)-c.Limit].Add(c.Window)
+		if leavesAt.After(allowedAt) {
+			allowedAt = leavesAt
+		}
+	}
+
+	return allowedAt, nil
+}
+
+// sentTimes returns the times messages were sent to the recipient since the given time, oldest
+// first. The database has every message that was marked as sent, while Redis loses what it
+// counted when it restarts or a write fails, so Redis is only used when it counts more messages,
+// e.g. because marking one of them as sent failed.
+func (d *MessageDispatcher) sentTimes(recipient string, since time.Time) ([]time.Time, error) {
+	sent, err := d.DB.GetSentTimes(recipient, since)
+	if err != nil {
+		return nil, err
+	}
+	if d.Redis == nil {
+		return sent, nil
+	}
+
+	members, err := d.Redis.ZRangeByScoreWithScores(context.Background(), sentToKey(recipient), &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		log.Printf("failed to read sent messages of recipient from Redis, using the database: %v", err)
+		return sent, nil
+	}
+	if len(members) <= len(sent) {
+		return sent, nil
+	}
+
+	sent = make([]time.Time, len(members))
+	for i, m := range members {
+		sent[i] = time.UnixMilli(int64(m.Score))
+	}
+	return sent, nil
+}
+
+// recordSent counts a message sent to the recipient in Redis, so it is counted even if marking it
+// as sent in the database failed. The database does not need to be updated, since it counts the
+// sent messages themselves.
+func (d *MessageDispatcher) recordSent(recipient string, id int, sentAt time.Time) {
+	if d.Redis == nil {
+		return
+	}
+
+	var longest time.Duration
+	for _, c := range d.FrequencyCaps {
+		longest = max(longest, c.Window)
+	}
+
+	ctx := context.Background()
+	key := sentToKey(recipient)
+	err := d.Redis.ZAdd(ctx, key, redis.Z{Score: float64(sentAt.UnixMilli()), Member: strconv.Itoa(id)}).Err()
+	if err == nil {
+		err = d.Redis.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(sentAt.Add(-longest).UnixMilli(), 10)).Err()
+	}
+	if err == nil {
+		err = d.Redis.Expire(ctx, key, longest).Err()
+	}
+	if err != nil {
+		log.Printf("failed to count sent message in Redis (id=%d): %v", id, err)
+	}
+}
+
+func sentToKey(recipient string) string {
+	return "sent_to:" + recipient
+}
+
+// recipientLocks serializes the messages to the same recipient, so concurrent messages cannot
+// all pass the frequency caps before any of them is counted
+type recipientLocks struct {
+	mu    sync.Mutex
+	locks map[string]*recipientLock
+}
+
+type recipientLock struct {
+	sync.Mutex
+	users int
+}
+
+// lock locks the recipient and returns the function to unlock it
+func (l *recipientLocks) lock(recipient string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*recipientLock{}
+	}
+	rl, ok := l.locks[recipient]
+	if !ok {
+		rl = &recipientLock{}
+		l.locks[recipient] = rl
+	}
+	rl.users++
+	l.mu.Unlock()
+
+	rl.Lock()
+	return func() {
+		rl.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		rl.users--
+		if rl.users == 0 {
+			delete(l.locks, recipient)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/taylankasap/message-sender/api"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	somethirdparty "github.com/taylankasap/message-sender/some_third_party"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMessageDispatcher_nextAllowedTime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2025, 5, 31, 12, 0, 0, 0, time.UTC)
+	sent := []time.Time{
+		now.Add(-23 * time.Hour),
+		now.Add(-5 * time.Hour),
+		now.Add(-30 * time.Minute),
+		now.Add(-10 * time.Minute),
+	}
+
+	t.Run("it should allow messages under every cap", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().GetSentTimes("+905551111111", now.Add(-24*time.Hour)).Return(sent, nil)
+
+		d := &MessageDispatcher{
+			DB:            mockDB,
+			FrequencyCaps: []FrequencyCap{{Limit: 5, Window: 24 * time.Hour}, {Limit: 3, Window: time.Hour}},
+		}
+		allowedAt, err := d.nextAllowedTime("+905551111111", now)
+		require.NoError(tt, err)
+		require.Zero(tt, allowedAt)
+	})
+
+	t.Run("it should return the time the recipient is under every cap again", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().GetSentTimes("+905551111111", now.Add(-24*time.Hour)).Return(sent, nil)
+
+		d := &MessageDispatcher{
+			DB:            mockDB,
+			FrequencyCaps: []FrequencyCap{{Limit: 3, Window: 24 * time.Hour}, {Limit: 2, Window: time.Hour}},
+		}
+		allowedAt, err := d.nextAllowedTime("+905551111111", now)
+		require.NoError(tt, err)
+
+		// the daily cap frees up when the message of 5 hours ago leaves the window, which
+		// is later than the hourly cap freeing up 30 minutes from now
+		require.Equal(tt, now.Add(19*time.Hour), allowedAt)
+	})
+
+	t.Run("it should use Redis if it counts messages the database missed", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockRedis := NewMockRedisCache(ctrl)
+		mockDB.EXPECT().GetSentTimes("+905551111111", now.Add(-time.Hour)).Return(nil, nil)
+
+		cmd := redis.NewZSliceCmd(context.Background())
+		cmd.SetVal([]redis.Z{{Score: float64(now.Add(-time.Minute).UnixMilli()), Member: "1"}})
+		mockRedis.EXPECT().ZRangeByScoreWithScores(gomock.Any(), "sent_to:+905551111111", &redis.ZRangeBy{
+			Min: fmt.Sprint(now.Add(-time.Hour).UnixMilli()),
+			Max: "+inf",
+		}).Return(cmd)
+
+		d := &MessageDispatcher{
+			DB:            mockDB,
+			Redis:         mockRedis,
+			FrequencyCaps: []FrequencyCap{{Limit: 1, Window: time.Hour}},
+		}
+		allowedAt, err := d.nextAllowedTime("+905551111111", now)
+		require.NoError(tt, err)
+		require.True(tt, now.Add(59*time.Minute).Equal(allowedAt))
+	})
+
+	t.Run("it should use the database if Redis lost the sent messages", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockRedis := NewMockRedisCache(ctrl)
+		mockDB.EXPECT().GetSentTimes("+905551111111", now.Add(-24*time.Hour)).Return(sent, nil)
+
+		cmd := redis.NewZSliceCmd(context.Background())
+		cmd.SetVal([]redis.Z{})
+		mockRedis.EXPECT().ZRangeByScoreWithScores(gomock.Any(), "sent_to:+905551111111", gomock.Any()).Return(cmd)
+
+		d := &MessageDispatcher{
+			DB:            mockDB,
+			Redis:         mockRedis,
+			FrequencyCaps: []FrequencyCap{{Limit: 3, Window: 24 * time.Hour}},
+		}
+		allowedAt, err := d.nextAllowedTime("+905551111111", now)
+		require.NoError(tt, err)
+		require.Equal(tt, now.Add(19*time.Hour), allowedAt)
+	})
+
+	t.Run("it should fall back to the database if Redis fails", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockRedis := NewMockRedisCache(ctrl)
+
+		cmd := redis.NewZSliceCmd(context.Background())
+		cmd.SetErr(fmt.Errorf("dummy error"))
+		mockRedis.EXPECT().ZRangeByScoreWithScores(gomock.Any(), gomock.Any(), gomock.Any()).Return(cmd)
+		mockDB.EXPECT().GetSentTimes("+905551111111", now.Add(-time.Hour)).Return(nil, nil)
+
+		d := &MessageDispatcher{
+			DB:            mockDB,
+			Redis:         mockRedis,
+			FrequencyCaps: []FrequencyCap{{Limit: 1, Window: time.Hour}},
+		}
+		allowedAt, err := d.nextAllowedTime("+905551111111", now)
+		require.NoError(tt, err)
+		require.Zero(tt, allowedAt)
+	})
+}
+
+func TestMessageDispatcher_recordSent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRedis := NewMockRedisCache(ctrl)
+	sentAt := time.Date(2025, 5, 31, 12, 0, 0, 0, time.UTC)
+
+	mockRedis.EXPECT().ZAdd(gomock.Any(), "sent_to:+905551111111", redis.Z{Score: float64(sentAt.UnixMilli()), Member: "123"}).Return(redis.NewIntCmd(context.Background()))
+	mockRedis.EXPECT().ZRemRangeByScore(gomock.Any(), "sent_to:+905551111111", "-inf", fmt.Sprintf("(%d", sentAt.Add(-24*time.Hour).UnixMilli())).Return(redis.NewIntCmd(context.Background()))
+	mockRedis.EXPECT().Expire(gomock.Any(), "sent_to:+905551111111", 24*time.Hour).Return(redis.NewBoolCmd(context.Background()))
+
+	d := &MessageDispatcher{
+		Redis:         mockRedis,
+		FrequencyCaps: []FrequencyCap{{Limit: 3, Window: time.Hour}, {Limit: 10, Window: 24 * time.Hour}},
+	}
+	d.recordSent("+905551111111", 123, sentAt)
+}
+
+func TestMessageDispatcher_processUnsentMessages_FrequencyCap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("it should postpone a message over the cap instead of sending it", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{Id: 123, Content: "Hello", Recipient: "+905551111111"}
+		sentAt := time.Now().Add(-time.Hour)
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(false, nil)
+		mockDB.EXPECT().GetSentTimes(msg.Recipient, gomock.Any()).Return([]time.Time{sentAt}, nil)
+		mockDB.EXPECT().DeferMessage(msg.Id, claimedAt, sentAt.Add(24*time.Hour)).Return(nil)
+
+		d := &MessageDispatcher{
+			DB:            mockDB,
+			Providers:     []Provider{{Name: "primary", Client: mockClient}},
+			FrequencyCaps: []FrequencyCap{{Limit: 1, Window: 24 * time.Hour}},
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("it should send a message under the cap", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{Id: 123, Content: "Hello", Recipient: "+905551111111"}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(false, nil)
+		mockDB.EXPECT().GetSentTimes(msg.Recipient, gomock.Any()).Return(nil, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+			&somethirdparty.SendMessageResponse{JSON202: &somethirdparty.APIResponse{}},
+			nil,
+		)
+		mockDB.EXPECT().MarkMessageAsSent(msg.Id, claimedAt, gomock.Any(), "primary", "").Return(nil)
+
+		d := &MessageDispatcher{
+			DB:            mockDB,
+			Providers:     []Provider{{Name: "primary", Client: mockClient}},
+			FrequencyCaps: []FrequencyCap{{Limit: 1, Window: 24 * time.Hour}},
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+}
+
+func TestRecipientLocks(t *testing.T) {
+	var locks recipientLocks
+	var wg sync.WaitGroup
+	active := 0
+	maxActive := 0
+	var mu sync.Mutex
+
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.lock("+905551111111")
+			defer unlock()
+
+			mu.Lock()
+			active++
+			maxActive = max(maxActive, active)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, maxActive)
+	require.Empty(t, locks.locks)
+}
@@ -4,48 +4,64 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"time"
+	"os"
+	_ "time/tzdata" // the runtime image has no time zone database, which quiet hours need
 
 	"github.com/taylankasap/message-sender/api"
+	"github.com/taylankasap/message-sender/config"
 
 	"github.com/taylankasap/message-sender/db"
 	somethirdparty "github.com/taylankasap/message-sender/some_third_party"
 )
 
 func main() {
+	// config, e.g. MESSAGE_SENDER_PROFILE=production reads config/config.production.yaml too
+	configFile := os.Getenv(config.EnvPrefix + "CONFIG")
+	if configFile == "" {
+		configFile = "config/config.yaml"
+	}
+	cfg, err := config.Load(configFile, os.Getenv(config.EnvPrefix+"PROFILE"))
+	if err != nil {
+		panic(err)
+	}
+
 	// database
-	database, databaseErr := db.New(&db.Config{Filename: "data/db.sqlite3"})
+	database, databaseErr := db.New(&db.Config{Filename: cfg.Database.Filename})
 	if databaseErr != nil {
 		panic(databaseErr)
 	}
 	defer database.Conn.Close()
 
-	if err := database.Seed(); err != nil {
-		panic(fmt.Errorf("failed to seed database: %w", err))
+	if cfg.Database.Seed {
+		if err := database.Seed(); err != nil {
+			panic(fmt.Errorf("failed to seed database: %w", err))
+		}
 	}
 
-	// third party client
-	const someThirdPartyBaseUrl = "https://webhook.site/e8318d16-f749-428e-9103-f1ca43e8c0dd"
-	client, err := somethirdparty.NewClientWithResponses(someThirdPartyBaseUrl)
-	if err != nil {
-		panic(err)
+	// third party providers, messages are sent with the first one that accepts them
+	providers := make([]Provider, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		client, err := somethirdparty.NewClientWithResponses(p.BaseURL, somethirdparty.WithHTTPClient(recordingDoer{&http.Client{}}))
+		if err != nil {
+			panic(err)
+		}
+		providers = append(providers, Provider{Name: p.Name, Client: client, Limiter: NewRateLimiter(p.RateLimit, p.Burst)})
 	}
 
 	// Redis
-	redisAddr := "redis:6379"
-	redisClient := NewRedisClient(redisAddr)
+	redisClient := NewRedisClient(cfg.Redis.Addr)
 
 	// message dispatcher
-	dispatcherConfig := &MessageDispatcherConfig{
-		Period:    2 * time.Minute,
-		BatchSize: 2,
+	dispatcher := NewMessageDispatcher(database, providers, redisClient, newMessageDispatcherConfig(cfg.Dispatcher))
+	if err := dispatcher.LoadRoutes(newRoutingTable(cfg.Routing)); err != nil {
+		panic(err)
 	}
-
-	dispatcher := NewMessageDispatcher(database, client, redisClient, dispatcherConfig)
 	go dispatcher.Start()
 
 	// API server
 	server := api.NewServer(database, dispatcher)
+	server.Router = dispatcher
+	server.MaxSegments = cfg.Dispatcher.MaxSegments
 
 	r := http.NewServeMux()
 
@@ -53,8 +69,40 @@ func main() {
 
 	s := &http.Server{
 		Handler: h,
-		Addr:    "0.0.0.0:8080",
+		Addr:    cfg.Server.Addr,
 	}
 
 	log.Fatal(s.ListenAndServe())
 }
+
+// newMessageDispatcherConfig converts the dispatcher settings of the config file
+func newMessageDispatcherConfig(cfg config.Dispatcher) *MessageDispatcherConfig {
+	dispatcherConfig := &MessageDispatcherConfig{
+		Period:           cfg.Period,
+		BatchSize:        cfg.BatchSize,
+		PriorityShare:    cfg.PriorityShare,
+		MaxAttempts:      cfg.MaxAttempts,
+		BaseBackoff:      cfg.BaseBackoff,
+		MaxBackoff:       cfg.MaxBackoff,
+		SendTimeout:      cfg.SendTimeout,
+		MaxSegments:      cfg.MaxSegments,
+		FailureThreshold: cfg.FailureThreshold,
+		ProviderCooldown: cfg.ProviderCooldown,
+	}
+	for _, c := range cfg.FrequencyCaps {
+		dispatcherConfig.FrequencyCaps = append(dispatcherConfig.FrequencyCaps, FrequencyCap{Limit: c.Limit, Window: c.Window})
+	}
+	if q := cfg.QuietHours; q != nil {
+		dispatcherConfig.QuietHours = &QuietHours{Start: q.Start, End: q.End, ExemptPriority: q.ExemptPriority}
+	}
+	return dispatcherConfig
+}
+
+// newRoutingTable converts the routing settings of the config file
+func newRoutingTable(cfg config.Routing) api.RoutingTable {
+	table := api.RoutingTable{DefaultProviders: cfg.DefaultProviders, Routes: []api.Route{}}
+	for _, r := range cfg.Routes {
+		table.Routes = append(table.Routes, api.Route{Prefix: r.Prefix, Providers: r.Providers})
+	}
+	return table
+}
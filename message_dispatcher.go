@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
+	"math/rand/v2"
+	"net/http"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/taylankasap/message-sender/api"
+	"github.com/taylankasap/message-sender/phonenumber"
 
 	"github.com/redis/go-redis/v9"
 	somethirdparty "github.com/taylankasap/message-sender/some_third_party"
@@ -17,24 +21,67 @@ import (
 //go:generate go tool mockgen --package=main --destination=mock_db_interface.go . DBInterface
 type DBInterface interface {
 	GetUnsentMessages(limit int) ([]api.Message, error)
-	GetSentMessages() ([]api.Message, error)
-	MarkMessageAsSent(id int, sentAt time.Time) error
-	MarkMessageAsInvalid(id int) error
+	GetOldestUnsentMessages(limit int) ([]api.Message, error)
+	MarkMessageAsSent(id int, claimedAt time.Time, sentAt time.Time, provider string, providerMessageId string) error
+	MarkMessageAsInvalid(id int, claimedAt time.Time, reason string) error
+	MarkMessageAsFailed(id int, claimedAt time.Time, lastError string) error
+	MarkMessageAsSending(id int) (api.Message, time.Time, bool, error)
+	RecordFailedAttempt(id int, claimedAt time.Time, lastError string, nextAttemptAt time.Time, provider string) error
+	ResetSendingMessages(claimedBefore time.Time, maxAttempts int) (int, error)
+	GetSentTimes(recipient string, since time.Time) ([]time.Time, error)
+	DeferMessage(id int, claimedAt time.Time, until time.Time) error
+	IsSuppressed(recipient string) (bool, error)
+	MarkMessageAsSuppressed(id int, claimedAt time.Time) error
+	GetRoutingTable() (api.RoutingTable, error)
+	SaveRoutingTable(table api.RoutingTable) error
 }
 
 //go:generate go tool mockgen --package=main --destination=mock_redis_cache.go . RedisCache
 type RedisCache interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd
+	ZRangeByScoreWithScores(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.ZSliceCmd
+	ZRemRangeByScore(ctx context.Context, key, min, max string) *redis.IntCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
 }
 
 type MessageDispatcher struct {
 	DB        DBInterface
-	Client    somethirdparty.ClientWithResponsesInterface
-	BatchSize int
-	Period    time.Duration
+	Providers []Provider    // Tried in this order until one of them accepts the message
+	BatchSize int           // Messages fetched at once, and sent at the same time at most
+	Period    time.Duration // Time to wait before checking again when there is nothing to send
+
+	// PriorityShare is the share of each batch that is filled by priority, the rest
+	// is filled by age so low priority messages are not starved. Zero fills the whole
+	// batch by priority.
+	PriorityShare float64
+
+	MaxAttempts int           // Messages are marked as failed after this many attempts, zero retries forever
+	BaseBackoff time.Duration // Delay before the first retry, doubled for every following one
+	MaxBackoff  time.Duration // Upper bound for the delay between retries, zero for no limit
+	SendTimeout time.Duration // Time to wait for the third party to respond, zero waits forever
+	MaxSegments int           // Messages longer than a single SMS are sent as up to this many parts, zero or one marks them invalid
+
+	FailureThreshold int           // Providers are skipped after failing this many times in a row, zero never skips them
+	ProviderCooldown time.Duration // Time to skip a failing provider for before trying it again
+
+	// FrequencyCaps limit the number of messages sent to the same recipient, messages over a
+	// cap are postponed until they can be sent without exceeding it
+	FrequencyCaps []FrequencyCap
+
+	// QuietHours postpone messages that would reach the recipient at night to the end of the
+	// quiet hours, nil sends messages at any time
+	QuietHours *QuietHours
 
 	Redis RedisCache // Optional, can be nil
 
+	health         providerHealth
+	routes         routingTable
+	recipientLocks recipientLocks
+
+	inFlight sync.WaitGroup // Messages being sent
+	slots    chan struct{}  // Limits the messages being sent to BatchSize
+
 	paused   bool
 	pauseMu  sync.Mutex
 	pauseCh  chan struct{}
@@ -42,95 +89,366 @@ type MessageDispatcher struct {
 }
 
 type MessageDispatcherConfig struct {
-	BatchSize int           // Number of messages to process in each batch
-	Period    time.Duration // Time period to wait before processing the next batch
+	BatchSize     int           // Number of messages to fetch at once and to send at the same time at most
+	Period        time.Duration // Time to wait before checking for messages again when there are none
+	PriorityShare float64       // Share of each batch reserved for high priority messages (0 reserves all of it)
+	MaxAttempts   int           // Number of attempts before a message is marked as failed (0 retries forever)
+	BaseBackoff   time.Duration // Delay before the first retry
+	MaxBackoff    time.Duration // Maximum delay between retries
+	SendTimeout   time.Duration // Time to wait for the third party to accept a message
+	MaxSegments   int           // Maximum number of parts of a concatenated SMS (0 or 1 only sends single SMS)
+
+	FailureThreshold int           // Number of consecutive failures before a provider is skipped (0 never skips it)
+	ProviderCooldown time.Duration // Time to skip a failing provider for
+
+	FrequencyCaps []FrequencyCap // Maximum number of messages per recipient in rolling windows
+	QuietHours    *QuietHours    // Time of day messages are not sent at in the time zone of the recipient (nil for none)
 }
 
-func NewMessageDispatcher(database DBInterface, client somethirdparty.ClientWithResponsesInterface, redisClient RedisCache, config *MessageDispatcherConfig) *MessageDispatcher {
+func NewMessageDispatcher(database DBInterface, providers []Provider, redisClient RedisCache, config *MessageDispatcherConfig) *MessageDispatcher {
 	d := &MessageDispatcher{
-		DB:        database,
-		Client:    client,
-		BatchSize: config.BatchSize,
-		Period:    config.Period,
-		Redis:     redisClient,
-		pauseCh:   make(chan struct{}),
-		resumeCh:  make(chan struct{}),
+		DB:               database,
+		Providers:        providers,
+		BatchSize:        config.BatchSize,
+		Period:           config.Period,
+		PriorityShare:    config.PriorityShare,
+		MaxAttempts:      config.MaxAttempts,
+		BaseBackoff:      config.BaseBackoff,
+		MaxBackoff:       config.MaxBackoff,
+		SendTimeout:      config.SendTimeout,
+		MaxSegments:      config.MaxSegments,
+		FailureThreshold: config.FailureThreshold,
+		ProviderCooldown: config.ProviderCooldown,
+		FrequencyCaps:    config.FrequencyCaps,
+		QuietHours:       config.QuietHours,
+		Redis:            redisClient,
+		pauseCh:          make(chan struct{}),
+		resumeCh:         make(chan struct{}),
 	}
 	return d
 }
 
+// Start sends messages until the app stops, as fast as the rate limits of the providers allow.
+// When there is nothing to send, it checks again after the period.
 func (d *MessageDispatcher) Start() {
-	ticker := time.NewTicker(d.Period)
-	defer ticker.Stop()
+	// nothing is being sent yet, so every message in sending was interrupted by a restart
+	d.recoverSendingMessages(time.Now())
 
 	for {
 		d.pauseMu.Lock()
 		paused := d.paused
 		pauseCh := d.pauseCh
+		resumeCh := d.resumeCh
 		d.pauseMu.Unlock()
 
 		if paused {
-			<-pauseCh // Block until resumed
+			<-resumeCh // Block until resumed
 			continue
 		}
 
-		d.processUnsentMessages()
-		<-ticker.C
+		// pausing cancels the context, so the dispatcher stops waiting for rate limits right away
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-pauseCh
+			cancel()
+		}()
+
+		for ctx.Err() == nil {
+			if d.processUnsentMessages(ctx) > 0 {
+				continue
+			}
+			select {
+			case <-time.After(d.Period):
+			case <-ctx.Done():
+			}
+		}
 	}
 }
 
-func (d *MessageDispatcher) processUnsentMessages() {
-	messages, err := d.DB.GetUnsentMessages(d.BatchSize)
+// nextBatch selects the messages to send by priority then age. When a priority share is
+// configured, only that part of the batch is selected by priority and the rest by age.
+func (d *MessageDispatcher) nextBatch() ([]api.Message, error) {
+	if d.PriorityShare <= 0 || d.PriorityShare >= 1 {
+		return d.DB.GetUnsentMessages(d.BatchSize)
+	}
+
+	reserved := int(math.Ceil(float64(d.BatchSize) * d.PriorityShare))
+	messages, err := d.DB.GetUnsentMessages(reserved)
+	if err != nil {
+		return nil, err
+	}
+
+	oldest, err := d.DB.GetOldestUnsentMessages(d.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[int]bool, len(messages))
+	for _, msg := range messages {
+		selected[msg.Id] = true
+	}
+	for _, msg := range oldest {
+		if len(messages) >= d.BatchSize {
+			break
+		}
+		if !selected[msg.Id] {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages, nil
+}
+
+// processUnsentMessages fetches the next batch and sends its messages one by one, each as soon
+// as a token of the rate limit of its provider is available. It returns the number of messages
+// fetched, and does not wait for them to be sent.
+func (d *MessageDispatcher) processUnsentMessages(ctx context.Context) int {
+	if stuckAfter := d.stuckAfter(); stuckAfter > 0 {
+		d.recoverSendingMessages(time.Now().Add(-stuckAfter))
+	}
+
+	messages, err := d.nextBatch()
 	if err != nil {
 		log.Printf("failed to fetch unsent messages: %v", err)
-		return
+		return 0
 	}
 
-	var wg sync.WaitGroup
+	if d.slots == nil {
+		d.slots = make(chan struct{}, max(d.BatchSize, 1))
+	}
 	for _, msg := range messages {
-		wg.Add(1)
-		go func(msg api.Message) {
-			defer wg.Done()
-			if len(msg.Content) > 160 {
-				log.Printf("message (id=%d) exceeds 160 character limit, marking as invalid", msg.Id)
-				err = d.DB.MarkMessageAsInvalid(msg.Id)
-				if err != nil {
-					log.Printf("failed to mark message as invalid (id=%d): %v", msg.Id, err)
-				}
-				return
-			}
-			ctx := context.Background()
-			resp, err := d.Client.SendMessageWithResponse(ctx, somethirdparty.Message{
-				Content: msg.Content,
-				To:      msg.Recipient,
-			})
-			if err != nil {
-				log.Printf("failed to send message (id=%d): %v", msg.Id, err)
-				return
-			}
-			if resp.JSON202 == nil {
-				log.Printf("unexpected response for message (id=%d)", msg.Id)
-				return
-			}
-			now := time.Now()
-			err = d.DB.MarkMessageAsSent(msg.Id, now)
-			if err != nil {
-				log.Printf("failed to update message status (id=%d): %v", msg.Id, err)
-			}
-			log.Printf("Message sent: id=%d, messageId=%s, sentAt=%s", msg.Id, resp.JSON202.MessageId, now)
+		select {
+		case d.slots <- struct{}{}:
+		case <-ctx.Done():
+			return len(messages)
+		}
 
-			if d.Redis != nil {
-				redisKey := "sent_message:" + strconv.Itoa(msg.Id)
-				redisValue := fmt.Sprintf(`{"messageId":"%s","sentAt":"%s"}`, resp.JSON202.MessageId, now.Format(time.RFC3339))
+		reserved, err := d.reserve(ctx, msg)
+		if err != nil {
+			<-d.slots
+			return len(messages)
+		}
 
-				err := d.Redis.Set(ctx, redisKey, redisValue, 0).Err()
-				if err != nil {
-					log.Printf("failed to cache sent message in Redis (id=%d): %v", msg.Id, err)
-				}
+		// claim the message only once it can be sent, so it is not picked up again or updated while
+		// it is being sent. It may have been updated since it was fetched, so the claimed message
+		// is checked and sent. The outcome is only recorded under the claim, so it is dropped if the
+		// message was recovered in the meantime.
+		claimedMsg, claimedAt, claimed, err := d.DB.MarkMessageAsSending(msg.Id)
+		if err != nil {
+			log.Printf("failed to mark message as sending (id=%d): %v", msg.Id, err)
+		} else if !claimed {
+			log.Printf("message (id=%d) is no longer unsent, skipping", msg.Id)
+		}
+		if err != nil || !claimed {
+			d.releaseToken(reserved)
+			<-d.slots
+			continue
+		}
+
+		d.inFlight.Add(1)
+		go func() {
+			defer func() {
+				<-d.slots
+				d.inFlight.Done()
+			}()
+			d.dispatch(ctx, claimedMsg, claimedAt, reserved)
+		}()
+	}
+
+	return len(messages)
+}
+
+// dispatch validates and sends a single message claimed at claimedAt and records the outcome.
+// reserved is the provider a rate limit token was taken from for the message, which is given
+// back if the message is not sent with it.
+func (d *MessageDispatcher) dispatch(ctx context.Context, msg api.Message, claimedAt time.Time, reserved string) {
+	handedOver := false
+	defer func() {
+		if !handedOver {
+			d.releaseToken(reserved)
+		}
+	}()
+
+	segmentation, err := api.ValidateSegments(msg.Content, d.MaxSegments)
+	if err != nil {
+		d.markAsInvalid(msg, claimedAt, err.Error())
+		return
+	}
+
+	// messages created before recipients were validated may have a malformed one, there
+	// is no point in paying the third party to reject them
+	recipient, err := phonenumber.Normalize(msg.Recipient)
+	if err != nil {
+		d.markAsInvalid(msg, claimedAt, api.ErrInvalidRecipient.Error())
+		return
+	}
+
+	suppressed, err := d.DB.IsSuppressed(recipient)
+	if err != nil {
+		log.Printf("failed to check suppression list (id=%d): %v", msg.Id, err)
+		d.deferMessage(msg, claimedAt, time.Now().Add(d.Period))
+		return
+	}
+	if suppressed {
+		log.Printf("recipient of message (id=%d) opted out, suppressing it", msg.Id)
+		if err := d.DB.MarkMessageAsSuppressed(msg.Id, claimedAt); err != nil {
+			log.Printf("failed to mark message as suppressed (id=%d): %v", msg.Id, err)
+		}
+		return
+	}
+
+	if endAt := d.quietHoursEnd(msg, recipient, time.Now()); !endAt.IsZero() {
+		log.Printf("message (id=%d) falls in the quiet hours of the recipient, postponing it to %s", msg.Id, endAt)
+		d.deferMessage(msg, claimedAt, endAt)
+		return
+	}
+
+	if len(d.FrequencyCaps) > 0 {
+		unlock := d.recipientLocks.lock(recipient)
+		defer unlock()
+
+		allowedAt, err := d.nextAllowedTime(recipient, time.Now())
+		if err != nil {
+			log.Printf("failed to check frequency caps (id=%d): %v", msg.Id, err)
+			d.deferMessage(msg, claimedAt, time.Now().Add(d.Period))
+			return
+		}
+		if !allowedAt.IsZero() {
+			log.Printf("message (id=%d) exceeds a frequency cap of the recipient, postponing it to %s", msg.Id, allowedAt)
+			d.deferMessage(msg, claimedAt, allowedAt)
+			return
+		}
+	}
+
+	handedOver = true
+	provider, resp, failure := d.send(ctx, msg, somethirdparty.Message{
+		Content:  msg.Content,
+		To:       recipient,
+		Encoding: somethirdparty.MessageEncoding(segmentation.Encoding),
+		Segments: segmentation.Segments,
+	}, reserved)
+	if failure != nil {
+		if failure.Permanent {
+			if err := d.DB.MarkMessageAsFailed(msg.Id, claimedAt, failure.Reason); err != nil {
+				log.Printf("failed to mark message as failed (id=%d): %v", msg.Id, err)
 			}
-		}(msg)
+			return
+		}
+		retryWith := ""
+		if failure.MaybeSent {
+			retryWith = provider.Name
+		}
+		d.handleFailedAttempt(msg, claimedAt, failure.Reason, retryWith)
+		return
+	}
+	now := time.Now()
+	err = d.DB.MarkMessageAsSent(msg.Id, claimedAt, now, provider.Name, resp.JSON202.MessageId)
+	if err != nil {
+		log.Printf("failed to update message status (id=%d): %v", msg.Id, err)
+	}
+	log.Printf("Message sent: id=%d, provider=%s, messageId=%s, sentAt=%s", msg.Id, provider.Name, resp.JSON202.MessageId, now)
+
+	if len(d.FrequencyCaps) > 0 {
+		d.recordSent(recipient, msg.Id, now)
+	}
+
+	if d.Redis != nil {
+		redisKey := "sent_message:" + strconv.Itoa(msg.Id)
+		redisValue := fmt.Sprintf(`{"provider":"%s","messageId":"%s","sentAt":"%s"}`, provider.Name, resp.JSON202.MessageId, now.Format(time.RFC3339))
+
+		err := d.Redis.Set(context.Background(), redisKey, redisValue, 0).Err()
+		if err != nil {
+			log.Printf("failed to cache sent message in Redis (id=%d): %v", msg.Id, err)
+		}
 	}
-	wg.Wait()
+}
+
+// deferMessage puts a claimed message back in the queue until the given time
+func (d *MessageDispatcher) deferMessage(msg api.Message, claimedAt time.Time, until time.Time) {
+	if err := d.DB.DeferMessage(msg.Id, claimedAt, until); err != nil {
+		log.Printf("failed to postpone message (id=%d): %v", msg.Id, err)
+	}
+}
+
+// markAsInvalid marks a message that can never be sent as invalid
+func (d *MessageDispatcher) markAsInvalid(msg api.Message, claimedAt time.Time, reason string) {
+	log.Printf("message (id=%d) is invalid: %s", msg.Id, reason)
+	if err := d.DB.MarkMessageAsInvalid(msg.Id, claimedAt, reason); err != nil {
+		log.Printf("failed to mark message as invalid (id=%d): %v", msg.Id, err)
+	}
+}
+
+// withIdempotencyKey adds a key derived from the message id to the request, so the third
+// party does not send a message twice if we retry it after it was already accepted
+func withIdempotencyKey(msg api.Message) somethirdparty.RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Idempotency-Key", "message-"+strconv.Itoa(msg.Id))
+		return nil
+	}
+}
+
+// recoverSendingMessages puts messages claimed before the given time that are still in sending
+// back in the queue, e.g. because the app stopped or their outcome could not be recorded. This
+// is safe because the third party ignores requests with a known idempotency key, and a send
+// that is still running cannot record its outcome once its claim is dropped. Every recovery
+// counts as an attempt, so a message that keeps crashing the app is eventually marked as failed.
+func (d *MessageDispatcher) recoverSendingMessages(claimedBefore time.Time) {
+	count, err := d.DB.ResetSendingMessages(claimedBefore, d.MaxAttempts)
+	if err != nil {
+		log.Printf("failed to recover messages stuck in sending: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("recovered %d message(s) stuck in sending", count)
+	}
+}
+
+// stuckAfter returns how long a message can be in sending before it is considered stuck, or
+// zero if sending has no timeout. Sending tries every provider with the send timeout, and the
+// time is doubled to leave room for waiting on rate limits and other messages to the recipient.
+func (d *MessageDispatcher) stuckAfter() time.Duration {
+	return 2 * d.SendTimeout * time.Duration(max(len(d.Providers), 1))
+}
+
+// handleFailedAttempt schedules a retry for the message, or marks it as failed once
+// the maximum number of attempts is reached. retryWith is the provider the message must be
+// retried with because it may have accepted the message, or empty to try every provider.
+func (d *MessageDispatcher) handleFailedAttempt(msg api.Message, claimedAt time.Time, reason string, retryWith string) {
+	attempts := msg.Attempts + 1
+	if d.MaxAttempts > 0 && attempts >= d.MaxAttempts {
+		log.Printf("message (id=%d) failed after %d attempts, marking as failed", msg.Id, attempts)
+		if err := d.DB.MarkMessageAsFailed(msg.Id, claimedAt, reason); err != nil {
+			log.Printf("failed to mark message as failed (id=%d): %v", msg.Id, err)
+		}
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(d.backoff(attempts))
+	if err := d.DB.RecordFailedAttempt(msg.Id, claimedAt, reason, nextAttemptAt, retryWith); err != nil {
+		log.Printf("failed to record failed attempt (id=%d): %v", msg.Id, err)
+	}
+}
+
+// backoff returns the delay before the next attempt, doubling with every attempt up
+// to MaxBackoff. Half of the delay is randomized so retries do not all happen at once.
+func (d *MessageDispatcher) backoff(attempts int) time.Duration {
+	if d.BaseBackoff <= 0 {
+		return 0
+	}
+
+	delay := d.BaseBackoff
+	for i := 1; i < attempts && delay < math.MaxInt64/2; i++ {
+		if d.MaxBackoff > 0 && delay >= d.MaxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	if d.MaxBackoff > 0 && delay > d.MaxBackoff {
+		delay = d.MaxBackoff
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
 }
 
 func (d *MessageDispatcher) Pause() {
@@ -3,6 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,37 +17,56 @@ import (
 	"go.uber.org/mock/gomock"
 )
 
+// claimedAt is the time the mocked database claims messages at
+var claimedAt = time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
 func TestMessageDispatcher_Start(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockDB := NewMockDBInterface(ctrl)
 	mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
 
-	t.Run("it should start dispatching the first batch of messages without waiting the period", func(tt *testing.T) {
-		fakeMsg := api.Message{
-			Id:        1,
-			Content:   "Hello",
-			Recipient: "+1234567890",
-			Status:    api.Unsent,
-		}
+	t.Run("it should keep sending batches without waiting the period while there are messages", func(tt *testing.T) {
+		first := api.Message{Id: 1, Content: "Hello", Recipient: "+1234567890", Status: api.Unsent}
+		second := api.Message{Id: 2, Content: "Hello again", Recipient: "+1234567890", Status: api.Unsent}
+		sent := make(chan int, 2)
 
-		// these should be called once
-		mockDB.EXPECT().GetUnsentMessages(1).Return([]api.Message{fakeMsg}, nil).Times(1)
+		mockDB.EXPECT().ResetSendingMessages(gomock.Any(), 0).Return(0, nil).Times(1)
+		gomock.InOrder(
+			mockDB.EXPECT().GetUnsentMessages(1).Return([]api.Message{first}, nil).Times(1),
+			mockDB.EXPECT().GetUnsentMessages(1).Return([]api.Message{second}, nil).Times(1),
+			mockDB.EXPECT().GetUnsentMessages(1).Return(nil, nil).AnyTimes(),
+		)
+		for _, msg := range []api.Message{first, second} {
+			mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil).Times(1)
+			mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(false, nil).Times(1)
+			mockDB.EXPECT().MarkMessageAsSent(msg.Id, claimedAt, gomock.Any(), "primary", "dummy-message-id").DoAndReturn(
+				func(id int, claimedAt time.Time, sentAt time.Time, provider string, providerMessageId string) error {
+					sent <- id
+					return nil
+				},
+			).Times(1)
+		}
 		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any(), gomock.Any()).Return(
 			&somethirdparty.SendMessageResponse{JSON202: &somethirdparty.APIResponse{MessageId: "dummy-message-id"}},
 			nil,
-		).Times(1)
-		mockDB.EXPECT().MarkMessageAsSent(fakeMsg.Id, gomock.Any()).Return(nil).Times(1)
+		).Times(2)
 
-		dispatcher := &MessageDispatcher{
-			DB:        mockDB,
-			Client:    mockClient,
+		dispatcher := NewMessageDispatcher(mockDB, []Provider{{Name: "primary", Client: mockClient}}, nil, &MessageDispatcherConfig{
 			BatchSize: 1,
 			Period:    2 * time.Minute,
-		}
+		})
 
 		go dispatcher.Start()
-		time.Sleep(1 * time.Millisecond)
+		for range 2 {
+			select {
+			case <-sent:
+			case <-time.After(time.Second):
+				tt.Fatal("messages were not sent continuously")
+			}
+		}
+		dispatcher.Pause()
+		dispatcher.inFlight.Wait()
 	})
 }
 
@@ -81,6 +103,48 @@ func TestMessageDispatcher_Resume(t *testing.T) {
 	require.False(t, d.paused, "dispatcher should remain unpaused after second Resume() call")
 }
 
+func TestMessageDispatcher_nextBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("it should fill the whole batch by priority if no priority share is set", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		expected := []api.Message{{Id: 3, Priority: 10}, {Id: 1}}
+		mockDB.EXPECT().GetUnsentMessages(2).Return(expected, nil)
+
+		d := &MessageDispatcher{DB: mockDB, BatchSize: 2}
+		messages, err := d.nextBatch()
+		require.NoError(tt, err)
+		require.Equal(tt, expected, messages)
+	})
+
+	t.Run("it should fill the rest of the batch with the oldest messages", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().GetUnsentMessages(2).Return([]api.Message{{Id: 5, Priority: 10}, {Id: 6, Priority: 10}}, nil)
+		mockDB.EXPECT().GetOldestUnsentMessages(4).Return([]api.Message{{Id: 1}, {Id: 5, Priority: 10}, {Id: 2}, {Id: 3}}, nil)
+
+		d := &MessageDispatcher{DB: mockDB, BatchSize: 4, PriorityShare: 0.5}
+		messages, err := d.nextBatch()
+		require.NoError(tt, err)
+
+		var ids []int
+		for _, msg := range messages {
+			ids = append(ids, msg.Id)
+		}
+		require.Equal(tt, []int{5, 6, 1, 2}, ids)
+	})
+
+	t.Run("error - should return the error if fetching the oldest messages fails", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().GetUnsentMessages(1).Return(nil, nil)
+		mockDB.EXPECT().GetOldestUnsentMessages(2).Return(nil, fmt.Errorf("dummy error"))
+
+		d := &MessageDispatcher{DB: mockDB, BatchSize: 2, PriorityShare: 0.5}
+		_, err := d.nextBatch()
+		require.Error(tt, err)
+	})
+}
+
 func TestMessageDispatcher_processUnsentMessages(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -91,43 +155,355 @@ func TestMessageDispatcher_processUnsentMessages(t *testing.T) {
 		mockRedis := NewMockRedisCache(ctrl)
 
 		msg := api.Message{
-			Id: 123,
+			Id:        123,
+			Recipient: "+1234567890",
 		}
 
 		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
-		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), somethirdparty.Message{}).Return(
+		mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(false, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), somethirdparty.Message{To: "+1234567890", Encoding: somethirdparty.GSM7, Segments: 1}, gomock.Any()).Return(
 			&somethirdparty.SendMessageResponse{
 				JSON202: &somethirdparty.APIResponse{},
 			},
 			nil,
 		)
-		mockDB.EXPECT().MarkMessageAsSent(msg.Id, gomock.Any()).Return(nil)
+		mockDB.EXPECT().MarkMessageAsSent(msg.Id, claimedAt, gomock.Any(), "primary", "").Return(nil)
 
 		cmd := redis.NewStatusCmd(context.Background())
 		cmd.SetVal("OK")
 		mockRedis.EXPECT().Set(gomock.Any(), "sent_message:123", gomock.Any(), time.Duration(0)).Return(cmd)
 
 		d := &MessageDispatcher{
-			DB:     mockDB,
-			Client: mockClient,
-			Redis:  mockRedis,
+			DB:        mockDB,
+			Providers: []Provider{{Name: "primary", Client: mockClient}},
+			Redis:     mockRedis,
 		}
-		d.processUnsentMessages()
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
 	})
 
 	t.Run("error - should mark message as invalid if message is too long", func(tt *testing.T) {
 		mockDB := NewMockDBInterface(ctrl)
 
 		msg := api.Message{
-			Content: string(make([]byte, 161)),
+			Content:   string(make([]byte, 161)),
+			Recipient: "+1234567890",
+		}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockDB.EXPECT().MarkMessageAsInvalid(msg.Id, claimedAt, "content is too long: it must fit in a single SMS (160 GSM-7 or 70 UCS-2 characters)").Return(nil)
+
+		d := &MessageDispatcher{
+			DB: mockDB,
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("error - should mark message as invalid if it does not fit in a UCS-2 SMS", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+
+		msg := api.Message{
+			Content:   strings.Repeat("ğ", 71),
+			Recipient: "+1234567890",
+		}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockDB.EXPECT().MarkMessageAsInvalid(msg.Id, claimedAt, "content is too long: it must fit in a single SMS (160 GSM-7 or 70 UCS-2 characters)").Return(nil)
+
+		d := &MessageDispatcher{
+			DB: mockDB,
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("success - should send long messages as concatenated SMS if allowed", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{
+			Id:        123,
+			Content:   strings.Repeat("ğ", 100),
+			Recipient: "+1234567890",
+		}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(false, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), somethirdparty.Message{Content: msg.Content, To: "+1234567890", Encoding: somethirdparty.UCS2, Segments: 2}, gomock.Any()).Return(
+			&somethirdparty.SendMessageResponse{JSON202: &somethirdparty.APIResponse{}},
+			nil,
+		)
+		mockDB.EXPECT().MarkMessageAsSent(msg.Id, claimedAt, gomock.Any(), "primary", "").Return(nil)
+
+		d := &MessageDispatcher{
+			DB:          mockDB,
+			Providers:   []Provider{{Name: "primary", Client: mockClient}},
+			MaxSegments: 3,
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("error - should mark message as invalid if it takes more segments than allowed", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+
+		msg := api.Message{
+			Id:        123,
+			Content:   strings.Repeat("a", 460),
+			Recipient: "+1234567890",
+		}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockDB.EXPECT().MarkMessageAsInvalid(msg.Id, claimedAt, "content is too long: it takes 4 SMS parts, at most 3 are allowed").Return(nil)
+
+		d := &MessageDispatcher{
+			DB:          mockDB,
+			MaxSegments: 3,
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("error - should mark message as invalid if the recipient is malformed", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+
+		msg := api.Message{
+			Id:        123,
+			Content:   "Hello",
+			Recipient: "5551111111",
 		}
 		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
-		mockDB.EXPECT().MarkMessageAsInvalid(msg.Id).Return(nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockDB.EXPECT().MarkMessageAsInvalid(msg.Id, claimedAt, api.ErrInvalidRecipient.Error()).Return(nil)
 
 		d := &MessageDispatcher{
 			DB: mockDB,
 		}
-		d.processUnsentMessages()
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("success - should send legacy recipients in E.164 format", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{
+			Id:        123,
+			Content:   "Hello",
+			Recipient: "+90 555 111 11 11",
+		}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().IsSuppressed("+905551111111").Return(false, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), somethirdparty.Message{Content: "Hello", To: "+905551111111", Encoding: somethirdparty.GSM7, Segments: 1}, gomock.Any()).Return(
+			&somethirdparty.SendMessageResponse{JSON202: &somethirdparty.APIResponse{}},
+			nil,
+		)
+		mockDB.EXPECT().MarkMessageAsSent(msg.Id, claimedAt, gomock.Any(), "primary", "").Return(nil)
+
+		d := &MessageDispatcher{
+			DB:        mockDB,
+			Providers: []Provider{{Name: "primary", Client: mockClient}},
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("error - should schedule a retry if sending fails", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{Id: 123, Recipient: "+1234567890", Attempts: 1}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(false, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("dummy error"))
+		mockDB.EXPECT().RecordFailedAttempt(msg.Id, claimedAt, "network error: dummy error", gomock.Any(), "primary").DoAndReturn(
+			func(id int, claimedAt time.Time, lastError string, nextAttemptAt time.Time, provider string) error {
+				require.WithinDuration(tt, time.Now().Add(2*time.Minute), nextAttemptAt, time.Minute+time.Second)
+				return nil
+			},
+		)
+
+		d := &MessageDispatcher{
+			DB:          mockDB,
+			Providers:   []Provider{{Name: "primary", Client: mockClient}},
+			MaxAttempts: 3,
+			BaseBackoff: time.Minute,
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("error - should mark message as failed after the last attempt", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{Id: 123, Recipient: "+1234567890", Attempts: 2}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(false, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+			&somethirdparty.SendMessageResponse{HTTPResponse: &http.Response{Status: "500 Internal Server Error", StatusCode: 500}},
+			nil,
+		)
+		mockDB.EXPECT().MarkMessageAsFailed(msg.Id, claimedAt, "server error: 500 Internal Server Error").Return(nil)
+
+		d := &MessageDispatcher{
+			DB:          mockDB,
+			Providers:   []Provider{{Name: "primary", Client: mockClient}},
+			MaxAttempts: 3,
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("error - should mark message as failed right away if the third party rejects it", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{Id: 123, Recipient: "+1234567890"}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(false, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+			&somethirdparty.SendMessageResponse{
+				HTTPResponse: &http.Response{Status: "400 Bad Request", StatusCode: 400},
+				JSON400:      &somethirdparty.Error{Code: "invalid_number", Message: "not a mobile number"},
+			},
+			nil,
+		)
+		mockDB.EXPECT().MarkMessageAsFailed(msg.Id, claimedAt, "invalid number: 400 Bad Request (invalid_number: not a mobile number)").Return(nil)
+
+		d := &MessageDispatcher{
+			DB:          mockDB,
+			Providers:   []Provider{{Name: "primary", Client: mockClient}},
+			MaxAttempts: 3,
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("error - should not send the message if it was already picked up", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{Id: 123, Recipient: "+1234567890"}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(api.Message{}, time.Time{}, false, nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		d := &MessageDispatcher{
+			DB:        mockDB,
+			Providers: []Provider{{Name: "primary", Client: mockClient}},
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("error - should suppress the message if the recipient opted out", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{Id: 123, Content: "Hello", Recipient: "+1234567890"}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(true, nil)
+		mockDB.EXPECT().MarkMessageAsSuppressed(msg.Id, claimedAt).Return(nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		d := &MessageDispatcher{
+			DB:        mockDB,
+			Providers: []Provider{{Name: "primary", Client: mockClient}},
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("error - should put the message back in the queue if the suppression list cannot be checked", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{Id: 123, Content: "Hello", Recipient: "+1234567890"}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(false, fmt.Errorf("dummy error"))
+		mockDB.EXPECT().DeferMessage(msg.Id, claimedAt, gomock.Any()).Return(nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		d := &MessageDispatcher{
+			DB:        mockDB,
+			Providers: []Provider{{Name: "primary", Client: mockClient}},
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("success - should send the message as it is when claimed if it was updated after it was fetched", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		fetched := api.Message{Id: 123, Content: "Hello", Recipient: "+905551111111"}
+		updated := api.Message{Id: 123, Content: "Hello again", Recipient: "+905552222222"}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{fetched}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(fetched.Id).Return(updated, claimedAt, true, nil)
+		mockDB.EXPECT().IsSuppressed(updated.Recipient).Return(false, nil)
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), somethirdparty.Message{Content: "Hello again", To: "+905552222222", Encoding: somethirdparty.GSM7, Segments: 1}, gomock.Any()).Return(
+			&somethirdparty.SendMessageResponse{JSON202: &somethirdparty.APIResponse{}},
+			nil,
+		)
+		mockDB.EXPECT().MarkMessageAsSent(updated.Id, claimedAt, gomock.Any(), "primary", "").Return(nil)
+
+		d := &MessageDispatcher{
+			DB:        mockDB,
+			Providers: []Provider{{Name: "primary", Client: mockClient}},
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("error - should give the rate limit token back if the message cannot be claimed", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{Id: 123, Content: "Hello", Recipient: "+1234567890"}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(api.Message{}, time.Time{}, false, nil)
+
+		limiter := NewRateLimiter(1, 1)
+		d := &MessageDispatcher{
+			DB:        mockDB,
+			Providers: []Provider{{Name: "primary", Client: mockClient, Limiter: limiter}},
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+
+		limiter.mu.Lock()
+		defer limiter.mu.Unlock()
+		require.InDelta(tt, 1, limiter.tokens, 0.01)
+	})
+
+	t.Run("error - should not claim messages while waiting for the rate limit if the dispatcher is paused", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		msg := api.Message{Id: 123, Content: "Hello", Recipient: "+1234567890"}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(gomock.Any()).Times(0)
+
+		limiter := NewRateLimiter(1, 1)
+		require.NoError(tt, limiter.Wait(context.Background()))
+		d := &MessageDispatcher{
+			DB:        mockDB,
+			Providers: []Provider{{Name: "primary", Client: mockClient, Limiter: limiter}},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		d.processUnsentMessages(ctx)
+		d.inFlight.Wait()
 	})
 
 	t.Run("error - should not call return if DB fetch fails", func(tt *testing.T) {
@@ -135,7 +511,7 @@ func TestMessageDispatcher_processUnsentMessages(t *testing.T) {
 		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
 
 		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return(nil, fmt.Errorf("dummy error"))
-		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any()).Return(
+		mockClient.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any(), gomock.Any()).Return(
 			nil,
 			nil,
 		).Times(0) // should not be called
@@ -144,6 +520,80 @@ func TestMessageDispatcher_processUnsentMessages(t *testing.T) {
 			DB: mockDB,
 		}
 
-		d.processUnsentMessages()
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+}
+
+func TestMessageDispatcher_backoff(t *testing.T) {
+	d := &MessageDispatcher{BaseBackoff: time.Minute, MaxBackoff: 10 * time.Minute}
+
+	cases := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: time.Minute},
+		{attempts: 2, expected: 2 * time.Minute},
+		{attempts: 4, expected: 8 * time.Minute},
+		{attempts: 5, expected: 10 * time.Minute},
+		{attempts: 100, expected: 10 * time.Minute},
+	}
+	for _, c := range cases {
+		delay := d.backoff(c.attempts)
+		require.GreaterOrEqual(t, delay, c.expected/2, "attempts=%d", c.attempts)
+		require.LessOrEqual(t, delay, c.expected, "attempts=%d", c.attempts)
+	}
+
+	require.Zero(t, (&MessageDispatcher{}).backoff(3), "no backoff should be applied if it is not configured")
+}
+
+func TestWithIdempotencyKey(t *testing.T) {
+	req := httptest.NewRequest("POST", "/send-message", nil)
+	require.NoError(t, withIdempotencyKey(api.Message{Id: 42})(context.Background(), req))
+	require.Equal(t, "message-42", req.Header.Get("Idempotency-Key"))
+}
+
+func TestMessageDispatcher_recoverSendingMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	claimedBefore := time.Now()
+	mockDB := NewMockDBInterface(ctrl)
+	mockDB.EXPECT().ResetSendingMessages(claimedBefore, 5).Return(2, nil)
+
+	d := &MessageDispatcher{DB: mockDB, MaxAttempts: 5}
+	d.recoverSendingMessages(claimedBefore)
+}
+
+func TestMessageDispatcher_processUnsentMessages_Recovery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("it should recover messages stuck in sending longer than every provider can take", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().ResetSendingMessages(gomock.Any(), 3).DoAndReturn(func(claimedBefore time.Time, maxAttempts int) (int, error) {
+			require.WithinDuration(tt, time.Now().Add(-40*time.Second), claimedBefore, time.Second)
+			return 1, nil
+		})
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return(nil, nil)
+
+		d := &MessageDispatcher{
+			DB:          mockDB,
+			Providers:   []Provider{{Name: "primary"}, {Name: "secondary"}},
+			SendTimeout: 10 * time.Second,
+			MaxAttempts: 3,
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+
+	t.Run("it should not recover messages while they may still be sent without a send timeout", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().ResetSendingMessages(gomock.Any(), gomock.Any()).Times(0)
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return(nil, nil)
+
+		d := &MessageDispatcher{DB: mockDB}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
 	})
 }
@@ -41,19 +41,63 @@ func (m *MockDBInterface) EXPECT() *MockDBInterfaceMockRecorder {
 	return m.recorder
 }
 
-// GetSentMessages mocks base method.
-func (m *MockDBInterface) GetSentMessages() ([]api.Message, error) {
+// DeferMessage mocks base method.
+func (m *MockDBInterface) DeferMessage(id int, claimedAt, until time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSentMessages")
+	ret := m.ctrl.Call(m, "DeferMessage", id, claimedAt, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeferMessage indicates an expected call of DeferMessage.
+func (mr *MockDBInterfaceMockRecorder) DeferMessage(id, claimedAt, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeferMessage", reflect.TypeOf((*MockDBInterface)(nil).DeferMessage), id, claimedAt, until)
+}
+
+// GetOldestUnsentMessages mocks base method.
+func (m *MockDBInterface) GetOldestUnsentMessages(limit int) ([]api.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOldestUnsentMessages", limit)
 	ret0, _ := ret[0].([]api.Message)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetSentMessages indicates an expected call of GetSentMessages.
-func (mr *MockDBInterfaceMockRecorder) GetSentMessages() *gomock.Call {
+// GetOldestUnsentMessages indicates an expected call of GetOldestUnsentMessages.
+func (mr *MockDBInterfaceMockRecorder) GetOldestUnsentMessages(limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOldestUnsentMessages", reflect.TypeOf((*MockDBInterface)(nil).GetOldestUnsentMessages), limit)
+}
+
+// GetRoutingTable mocks base method.
+func (m *MockDBInterface) GetRoutingTable() (api.RoutingTable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRoutingTable")
+	ret0, _ := ret[0].(api.RoutingTable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRoutingTable indicates an expected call of GetRoutingTable.
+func (mr *MockDBInterfaceMockRecorder) GetRoutingTable() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSentMessages", reflect.TypeOf((*MockDBInterface)(nil).GetSentMessages))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoutingTable", reflect.TypeOf((*MockDBInterface)(nil).GetRoutingTable))
+}
+
+// GetSentTimes mocks base method.
+func (m *MockDBInterface) GetSentTimes(recipient string, since time.Time) ([]time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSentTimes", recipient, since)
+	ret0, _ := ret[0].([]time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSentTimes indicates an expected call of GetSentTimes.
+func (mr *MockDBInterfaceMockRecorder) GetSentTimes(recipient, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSentTimes", reflect.TypeOf((*MockDBInterface)(nil).GetSentTimes), recipient, since)
 }
 
 // GetUnsentMessages mocks base method.
@@ -71,30 +115,133 @@ func (mr *MockDBInterfaceMockRecorder) GetUnsentMessages(limit any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUnsentMessages", reflect.TypeOf((*MockDBInterface)(nil).GetUnsentMessages), limit)
 }
 
+// IsSuppressed mocks base method.
+func (m *MockDBInterface) IsSuppressed(recipient string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSuppressed", recipient)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsSuppressed indicates an expected call of IsSuppressed.
+func (mr *MockDBInterfaceMockRecorder) IsSuppressed(recipient any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSuppressed", reflect.TypeOf((*MockDBInterface)(nil).IsSuppressed), recipient)
+}
+
+// MarkMessageAsFailed mocks base method.
+func (m *MockDBInterface) MarkMessageAsFailed(id int, claimedAt time.Time, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkMessageAsFailed", id, claimedAt, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkMessageAsFailed indicates an expected call of MarkMessageAsFailed.
+func (mr *MockDBInterfaceMockRecorder) MarkMessageAsFailed(id, claimedAt, lastError any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessageAsFailed", reflect.TypeOf((*MockDBInterface)(nil).MarkMessageAsFailed), id, claimedAt, lastError)
+}
+
 // MarkMessageAsInvalid mocks base method.
-func (m *MockDBInterface) MarkMessageAsInvalid(id int) error {
+func (m *MockDBInterface) MarkMessageAsInvalid(id int, claimedAt time.Time, reason string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MarkMessageAsInvalid", id)
+	ret := m.ctrl.Call(m, "MarkMessageAsInvalid", id, claimedAt, reason)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // MarkMessageAsInvalid indicates an expected call of MarkMessageAsInvalid.
-func (mr *MockDBInterfaceMockRecorder) MarkMessageAsInvalid(id any) *gomock.Call {
+func (mr *MockDBInterfaceMockRecorder) MarkMessageAsInvalid(id, claimedAt, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessageAsInvalid", reflect.TypeOf((*MockDBInterface)(nil).MarkMessageAsInvalid), id, claimedAt, reason)
+}
+
+// MarkMessageAsSending mocks base method.
+func (m *MockDBInterface) MarkMessageAsSending(id int) (api.Message, time.Time, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkMessageAsSending", id)
+	ret0, _ := ret[0].(api.Message)
+	ret1, _ := ret[1].(time.Time)
+	ret2, _ := ret[2].(bool)
+	ret3, _ := ret[3].(error)
+	return ret0, ret1, ret2, ret3
+}
+
+// MarkMessageAsSending indicates an expected call of MarkMessageAsSending.
+func (mr *MockDBInterfaceMockRecorder) MarkMessageAsSending(id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessageAsInvalid", reflect.TypeOf((*MockDBInterface)(nil).MarkMessageAsInvalid), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessageAsSending", reflect.TypeOf((*MockDBInterface)(nil).MarkMessageAsSending), id)
 }
 
 // MarkMessageAsSent mocks base method.
-func (m *MockDBInterface) MarkMessageAsSent(id int, sentAt time.Time) error {
+func (m *MockDBInterface) MarkMessageAsSent(id int, claimedAt, sentAt time.Time, provider, providerMessageId string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MarkMessageAsSent", id, sentAt)
+	ret := m.ctrl.Call(m, "MarkMessageAsSent", id, claimedAt, sentAt, provider, providerMessageId)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // MarkMessageAsSent indicates an expected call of MarkMessageAsSent.
-func (mr *MockDBInterfaceMockRecorder) MarkMessageAsSent(id, sentAt any) *gomock.Call {
+func (mr *MockDBInterfaceMockRecorder) MarkMessageAsSent(id, claimedAt, sentAt, provider, providerMessageId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessageAsSent", reflect.TypeOf((*MockDBInterface)(nil).MarkMessageAsSent), id, claimedAt, sentAt, provider, providerMessageId)
+}
+
+// MarkMessageAsSuppressed mocks base method.
+func (m *MockDBInterface) MarkMessageAsSuppressed(id int, claimedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkMessageAsSuppressed", id, claimedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkMessageAsSuppressed indicates an expected call of MarkMessageAsSuppressed.
+func (mr *MockDBInterfaceMockRecorder) MarkMessageAsSuppressed(id, claimedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessageAsSuppressed", reflect.TypeOf((*MockDBInterface)(nil).MarkMessageAsSuppressed), id, claimedAt)
+}
+
+// RecordFailedAttempt mocks base method.
+func (m *MockDBInterface) RecordFailedAttempt(id int, claimedAt time.Time, lastError string, nextAttemptAt time.Time, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordFailedAttempt", id, claimedAt, lastError, nextAttemptAt, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordFailedAttempt indicates an expected call of RecordFailedAttempt.
+func (mr *MockDBInterfaceMockRecorder) RecordFailedAttempt(id, claimedAt, lastError, nextAttemptAt, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordFailedAttempt", reflect.TypeOf((*MockDBInterface)(nil).RecordFailedAttempt), id, claimedAt, lastError, nextAttemptAt, provider)
+}
+
+// ResetSendingMessages mocks base method.
+func (m *MockDBInterface) ResetSendingMessages(claimedBefore time.Time, maxAttempts int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetSendingMessages", claimedBefore, maxAttempts)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetSendingMessages indicates an expected call of ResetSendingMessages.
+func (mr *MockDBInterfaceMockRecorder) ResetSendingMessages(claimedBefore, maxAttempts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetSendingMessages", reflect.TypeOf((*MockDBInterface)(nil).ResetSendingMessages), claimedBefore, maxAttempts)
+}
+
+// SaveRoutingTable mocks base method.
+func (m *MockDBInterface) SaveRoutingTable(table api.RoutingTable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRoutingTable", table)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveRoutingTable indicates an expected call of SaveRoutingTable.
+func (mr *MockDBInterfaceMockRecorder) SaveRoutingTable(table any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessageAsSent", reflect.TypeOf((*MockDBInterface)(nil).MarkMessageAsSent), id, sentAt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRoutingTable", reflect.TypeOf((*MockDBInterface)(nil).SaveRoutingTable), table)
 }
@@ -0,0 +1,37 @@
+// Package phonenumber validates phone numbers and normalizes them to the E.164 format
+package phonenumber
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidNumber = errors.New("phone number must be in E.164 format, e.g. +905551111111")
+
+// e164Pattern matches a '+' followed by a country code that does not start with zero and
+// at most 15 digits in total. Numbers shorter than 7 digits are not reachable by SMS.
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// separators are the characters commonly used to group the digits of a phone number
+var separators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// IsValid reports whether the number is already in E.164 format
+func IsValid(number string) bool {
+	return e164Pattern.MatchString(number)
+}
+
+// Normalize returns the number in E.164 format. Spaces, dashes, dots and parentheses are
+// removed and the international call prefix 00 is replaced by '+'. Numbers without a
+// country code are rejected, since there is no way to tell which country they belong to.
+func Normalize(number string) (string, error) {
+	normalized := separators.Replace(strings.TrimSpace(number))
+	if strings.HasPrefix(normalized, "00") {
+		normalized = "+" + normalized[2:]
+	}
+
+	if !IsValid(normalized) {
+		return "", ErrInvalidNumber
+	}
+	return normalized, nil
+}
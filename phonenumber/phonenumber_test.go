@@ -0,0 +1,49 @@
+package phonenumber
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Run("success - should normalize numbers to E.164", func(tt *testing.T) {
+		numbers := map[string]string{
+			"+905551111111":      "+905551111111",
+			" +90 555 111 11 11": "+905551111111",
+			"+1 (418) 123-4567":  "+14181234567",
+			"+82.1260.542.022":   "+821260542022",
+			"00905551111111":     "+905551111111",
+		}
+		for number, expected := range numbers {
+			actual, err := Normalize(number)
+			require.NoError(tt, err, number)
+			require.Equal(tt, expected, actual, number)
+		}
+	})
+
+	t.Run("error - should reject malformed numbers", func(tt *testing.T) {
+		numbers := []string{
+			"",
+			"not a number",
+			"5551111111",
+			"+",
+			"+0905551111111",
+			"+12345",
+			"+1234567890123456",
+			"++905551111111",
+			"+90555111111a",
+			"+90/555/111/11/11",
+		}
+		for _, number := range numbers {
+			_, err := Normalize(number)
+			require.ErrorIs(tt, err, ErrInvalidNumber, number)
+		}
+	})
+}
+
+func TestIsValid(t *testing.T) {
+	require.True(t, IsValid("+905551111111"))
+	require.False(t, IsValid("+90 555 111 11 11"))
+	require.False(t, IsValid("00905551111111"))
+}
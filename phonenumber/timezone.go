@@ -0,0 +1,106 @@
+package phonenumber
+
+import "strings"
+
+// countryTimeZones maps the country calling codes to the IANA time zone of the country.
+// Countries spanning several time zones (e.g. the United States, Russia or Brazil) are left
+// out, since the number alone does not tell which of them the recipient is in. Countries
+// with a small share of their population in another time zone (e.g. the Canary Islands of
+// Spain) use the time zone of the majority.
+var countryTimeZones = map[string]string{
+	"20":  "Africa/Cairo",
+	"27":  "Africa/Johannesburg",
+	"30":  "Europe/Athens",
+	"31":  "Europe/Amsterdam",
+	"32":  "Europe/Brussels",
+	"33":  "Europe/Paris",
+	"34":  "Europe/Madrid",
+	"36":  "Europe/Budapest",
+	"39":  "Europe/Rome",
+	"40":  "Europe/Bucharest",
+	"41":  "Europe/Zurich",
+	"43":  "Europe/Vienna",
+	"44":  "Europe/London",
+	"45":  "Europe/Copenhagen",
+	"46":  "Europe/Stockholm",
+	"47":  "Europe/Oslo",
+	"48":  "Europe/Warsaw",
+	"49":  "Europe/Berlin",
+	"51":  "America/Lima",
+	"53":  "America/Havana",
+	"54":  "America/Argentina/Buenos_Aires",
+	"57":  "America/Bogota",
+	"58":  "America/Caracas",
+	"60":  "Asia/Kuala_Lumpur",
+	"63":  "Asia/Manila",
+	"64":  "Pacific/Auckland",
+	"65":  "Asia/Singapore",
+	"66":  "Asia/Bangkok",
+	"81":  "Asia/Tokyo",
+	"82":  "Asia/Seoul",
+	"84":  "Asia/Ho_Chi_Minh",
+	"86":  "Asia/Shanghai",
+	"90":  "Europe/Istanbul",
+	"91":  "Asia/Kolkata",
+	"92":  "Asia/Karachi",
+	"94":  "Asia/Colombo",
+	"98":  "Asia/Tehran",
+	"212": "Africa/Casablanca",
+	"213": "Africa/Algiers",
+	"216": "Africa/Tunis",
+	"234": "Africa/Lagos",
+	"254": "Africa/Nairobi",
+	"351": "Europe/Lisbon",
+	"352": "Europe/Luxembourg",
+	"353": "Europe/Dublin",
+	"354": "Atlantic/Reykjavik",
+	"355": "Europe/Tirane",
+	"356": "Europe/Malta",
+	"357": "Asia/Nicosia",
+	"358": "Europe/Helsinki",
+	"359": "Europe/Sofia",
+	"370": "Europe/Vilnius",
+	"371": "Europe/Riga",
+	"372": "Europe/Tallinn",
+	"373": "Europe/Chisinau",
+	"374": "Asia/Yerevan",
+	"375": "Europe/Minsk",
+	"380": "Europe/Kyiv",
+	"381": "Europe/Belgrade",
+	"382": "Europe/Podgorica",
+	"385": "Europe/Zagreb",
+	"386": "Europe/Ljubljana",
+	"387": "Europe/Sarajevo",
+	"389": "Europe/Skopje",
+	"420": "Europe/Prague",
+	"421": "Europe/Bratislava",
+	"880": "Asia/Dhaka",
+	"886": "Asia/Taipei",
+	"961": "Asia/Beirut",
+	"962": "Asia/Amman",
+	"964": "Asia/Baghdad",
+	"965": "Asia/Kuwait",
+	"966": "Asia/Riyadh",
+	"971": "Asia/Dubai",
+	"972": "Asia/Jerusalem",
+	"973": "Asia/Bahrain",
+	"974": "Asia/Qatar",
+	"994": "Asia/Baku",
+	"995": "Asia/Tbilisi",
+}
+
+// TimeZone returns the IANA time zone of the country of a number in E.164 format, or an
+// empty string if the country is not known or spans several time zones. Calling codes are
+// prefix free, so at most one of them matches the number.
+func TimeZone(number string) string {
+	if !IsValid(number) {
+		return ""
+	}
+	digits := strings.TrimPrefix(number, "+")
+	for length := 1; length <= 3; length++ {
+		if zone, ok := countryTimeZones[digits[:length]]; ok {
+			return zone
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package phonenumber
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeZone(t *testing.T) {
+	t.Run("success - should return the time zone of the country code", func(tt *testing.T) {
+		numbers := map[string]string{
+			"+905551111111": "Europe/Istanbul",
+			"+447911123456": "Europe/London",
+			"+33612345678":  "Europe/Paris",
+			"+35312345678":  "Europe/Dublin",
+			"+97150123456":  "Asia/Dubai",
+		}
+		for number, expected := range numbers {
+			require.Equal(tt, expected, TimeZone(number), number)
+		}
+	})
+
+	t.Run("it should not guess the time zone of countries spanning several time zones", func(tt *testing.T) {
+		for _, number := range []string{"+14181234567", "+79161234567", "+5511912345678", "+61412345678"} {
+			require.Empty(tt, TimeZone(number), number)
+		}
+	})
+
+	t.Run("it should return an empty string for malformed numbers", func(tt *testing.T) {
+		require.Empty(tt, TimeZone("905551111111"))
+		require.Empty(tt, TimeZone(""))
+	})
+
+	t.Run("it should only map to known time zones", func(tt *testing.T) {
+		for code, zone := range countryTimeZones {
+			_, err := time.LoadLocation(zone)
+			require.NoError(tt, err, code)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/taylankasap/message-sender/api"
+	"github.com/taylankasap/message-sender/phonenumber"
+
+	somethirdparty "github.com/taylankasap/message-sender/some_third_party"
+)
+
+// Provider is a third party the messages can be sent with
+type Provider struct {
+	Name    string // Stored on every message sent with this provider
+	Client  somethirdparty.ClientWithResponsesInterface
+	Limiter *RateLimiter // Optional, can be nil to send as fast as possible
+}
+
+// providerHealth tracks the consecutive failures of every provider, so a provider that keeps
+// failing is skipped for a while instead of slowing down every message
+type providerHealth struct {
+	mu        sync.Mutex
+	failures  map[string]int
+	downUntil map[string]time.Time
+}
+
+// isDown reports whether the provider failed too many times in a row and is cooling down
+func (h *providerHealth) isDown(name string, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return now.Before(h.downUntil[name])
+}
+
+// succeeded marks the provider as healthy again
+func (h *providerHealth) succeeded(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.failures, name)
+	delete(h.downUntil, name)
+}
+
+// failed counts a failure of the provider and takes it down for the cooldown once it failed
+// threshold times in a row. After the cooldown the provider is tried again, and a single
+// failure takes it down again. A zero threshold never takes providers down.
+func (h *providerHealth) failed(name string, threshold int, cooldown time.Duration, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.failures == nil {
+		h.failures = map[string]int{}
+		h.downUntil = map[string]time.Time{}
+	}
+
+	h.failures[name]++
+	if threshold > 0 && h.failures[name] >= threshold {
+		if !now.Before(h.downUntil[name]) {
+			log.Printf("provider %s failed %d times in a row, skipping it for %s", name, h.failures[name], cooldown)
+		}
+		h.downUntil[name] = now.Add(cooldown)
+	}
+}
+
+// providersInOrder returns the healthy providers of the recipient in the routed order, followed
+// by the ones that are down. Providers that are down are only tried when every healthy one
+// failed, since trying them is still better than not sending the message at all.
+func (d *MessageDispatcher) providersInOrder(recipient string) []Provider {
+	now := time.Now()
+	providers := d.routeProviders(recipient)
+	healthy := make([]Provider, 0, len(providers))
+	var down []Provider
+	for _, provider := range providers {
+		if d.health.isDown(provider.Name, now) {
+			down = append(down, provider)
+		} else {
+			healthy = append(healthy, provider)
+		}
+	}
+	return append(healthy, down...)
+}
+
+// providersFor returns the providers to try the message with. A message whose outcome is unknown
+// with a provider, e.g. because the provider timed out, is only retried with that provider, so
+// its idempotency key keeps it from being sent twice.
+func (d *MessageDispatcher) providersFor(msg api.Message, recipient string) []Provider {
+	if msg.Provider != nil {
+		for _, provider := range d.Providers {
+			if provider.Name == *msg.Provider {
+				return []Provider{provider}
+			}
+		}
+	}
+	return d.providersInOrder(recipient)
+}
+
+// reserve waits for the rate limit of the provider the message is sent with first and returns its
+// name, so messages are only claimed as fast as they can be sent. It returns an error if the
+// context is done first.
+func (d *MessageDispatcher) reserve(ctx context.Context, msg api.Message) (string, error) {
+	recipient, err := phonenumber.Normalize(msg.Recipient)
+	if err != nil {
+		recipient = msg.Recipient
+	}
+
+	providers := d.providersFor(msg, recipient)
+	if len(providers) == 0 {
+		return "", nil
+	}
+	if err := providers[0].Limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+	return providers[0].Name, nil
+}
+
+// releaseToken gives back the token reserve took from the rate limit of the named provider
+func (d *MessageDispatcher) releaseToken(name string) {
+	for _, provider := range d.Providers {
+		if provider.Name == name {
+			provider.Limiter.release()
+			return
+		}
+	}
+}
+
+// send tries the providers in order until one of them accepts the message, and returns that
+// provider. A permanent failure is returned right away, since the other providers would reject
+// the message too, and so is a failure of a provider that may have accepted the message after
+// all, since another provider would send it twice. Otherwise the failure of the last provider is
+// returned. reserved is the provider reserve took a token from, or empty if no token was taken.
+func (d *MessageDispatcher) send(ctx context.Context, msg api.Message, body somethirdparty.Message, reserved string) (Provider, *somethirdparty.SendMessageResponse, *sendFailure) {
+	providers := d.providersFor(msg, body.To)
+	if reserved != "" && (len(providers) == 0 || providers[0].Name != reserved) {
+		// the message is routed elsewhere now, e.g. because its recipient was updated
+		d.releaseToken(reserved)
+		reserved = ""
+	}
+
+	failure := &sendFailure{Kind: failureUnexpected, Reason: "no provider is configured"}
+	for i, provider := range providers {
+		var resp *somethirdparty.SendMessageResponse
+		resp, failure = d.sendWith(ctx, provider, msg, body, i == 0 && reserved != "")
+		if failure == nil {
+			d.health.succeeded(provider.Name)
+			return provider, resp, nil
+		}
+
+		log.Printf("failed to send message (id=%d) with provider %s: %s", msg.Id, provider.Name, failure.Reason)
+		if failure.Permanent {
+			return provider, nil, failure
+		}
+		d.health.failed(provider.Name, d.FailureThreshold, d.ProviderCooldown, time.Now())
+		if failure.MaybeSent {
+			return provider, nil, failure
+		}
+	}
+	return Provider{}, nil, failure
+}
+
+// sendWith sends the message with a single provider, waiting for its rate limit unless a token
+// was already reserved. Only the wait is cancelled with the context, a request that was made is
+// left to finish so its outcome is known.
+func (d *MessageDispatcher) sendWith(ctx context.Context, provider Provider, msg api.Message, body somethirdparty.Message, reserved bool) (*somethirdparty.SendMessageResponse, *sendFailure) {
+	if !reserved {
+		if err := provider.Limiter.Wait(ctx); err != nil {
+			return nil, newSendFailure(failureRateLimited, false, err.Error())
+		}
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if d.SendTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.SendTimeout)
+		defer cancel()
+	}
+
+	ctx, recorder := withResponseRecorder(ctx)
+	resp, err := provider.Client.SendMessageWithResponse(ctx, body, withIdempotencyKey(msg))
+	if resp == nil && recorder.resp != nil {
+		resp = &somethirdparty.SendMessageResponse{HTTPResponse: recorder.resp}
+	}
+	return resp, classifySendResult(resp, err)
+}
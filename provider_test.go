@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/taylankasap/message-sender/api"
+
+	"github.com/stretchr/testify/require"
+	somethirdparty "github.com/taylankasap/message-sender/some_third_party"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMessageDispatcher_send(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	msg := api.Message{Id: 123, Content: "Hello", Recipient: "+1234567890"}
+	body := somethirdparty.Message{Content: "Hello", To: "+1234567890"}
+	accepted := &somethirdparty.SendMessageResponse{JSON202: &somethirdparty.APIResponse{MessageId: "dummy-message-id"}}
+	unavailable := &somethirdparty.SendMessageResponse{HTTPResponse: &http.Response{Status: "503 Service Unavailable", StatusCode: 503}}
+
+	t.Run("success - should fail over to the next provider", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		secondary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		primary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(unavailable, nil)
+		secondary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(accepted, nil)
+
+		d := &MessageDispatcher{
+			Providers: []Provider{{Name: "primary", Client: primary}, {Name: "secondary", Client: secondary}},
+		}
+		provider, resp, failure := d.send(context.Background(), msg, body, "")
+		require.Nil(tt, failure)
+		require.Equal(tt, "secondary", provider.Name)
+		require.Equal(tt, accepted, resp)
+	})
+
+	t.Run("error - should not fail over if the message is rejected", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		secondary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		primary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(
+			&somethirdparty.SendMessageResponse{
+				HTTPResponse: &http.Response{Status: "400 Bad Request", StatusCode: 400},
+				JSON400:      &somethirdparty.Error{Code: "invalid_number", Message: "not a mobile number"},
+			},
+			nil,
+		)
+
+		d := &MessageDispatcher{
+			Providers: []Provider{{Name: "primary", Client: primary}, {Name: "secondary", Client: secondary}},
+		}
+		_, _, failure := d.send(context.Background(), msg, body, "")
+		require.NotNil(tt, failure)
+		require.True(tt, failure.Permanent)
+	})
+
+	t.Run("error - should return the last failure if every provider fails", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		secondary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		primary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(unavailable, nil)
+		secondary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(nil, fmt.Errorf("dummy error"))
+
+		d := &MessageDispatcher{
+			Providers: []Provider{{Name: "primary", Client: primary}, {Name: "secondary", Client: secondary}},
+		}
+		_, _, failure := d.send(context.Background(), msg, body, "")
+		require.NotNil(tt, failure)
+		require.Equal(tt, "network error: dummy error", failure.Reason)
+	})
+
+	t.Run("success - should wait for the rate limit of the provider", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		primary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(accepted, nil).Times(2)
+
+		d := &MessageDispatcher{
+			Providers: []Provider{{Name: "primary", Client: primary, Limiter: NewRateLimiter(50, 1)}},
+		}
+
+		start := time.Now()
+		for range 2 {
+			_, _, failure := d.send(context.Background(), msg, body, "")
+			require.Nil(tt, failure)
+		}
+		require.GreaterOrEqual(tt, time.Since(start), 15*time.Millisecond)
+	})
+
+	t.Run("success - should not wait for the rate limit again if a token was reserved", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		primary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(accepted, nil)
+
+		d := &MessageDispatcher{
+			Providers: []Provider{{Name: "primary", Client: primary, Limiter: NewRateLimiter(1, 1)}},
+		}
+
+		reserved, err := d.reserve(context.Background(), msg)
+		require.NoError(tt, err)
+		require.Equal(tt, "primary", reserved)
+
+		start := time.Now()
+		_, _, failure := d.send(context.Background(), msg, body, reserved)
+		require.Nil(tt, failure)
+		require.Less(tt, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("success - should give the reserved token back if the message is routed elsewhere", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		secondary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		secondary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(accepted, nil)
+
+		limiter := NewRateLimiter(1, 1)
+		d := &MessageDispatcher{
+			Providers: []Provider{{Name: "primary", Client: primary, Limiter: limiter}, {Name: "secondary", Client: secondary}},
+		}
+
+		reserved, err := d.reserve(context.Background(), msg)
+		require.NoError(tt, err)
+		require.Equal(tt, "primary", reserved)
+
+		d.routes.set(api.RoutingTable{DefaultProviders: []string{"secondary"}, Routes: []api.Route{}})
+		provider, _, failure := d.send(context.Background(), msg, body, reserved)
+		require.Nil(tt, failure)
+		require.Equal(tt, "secondary", provider.Name)
+
+		limiter.mu.Lock()
+		defer limiter.mu.Unlock()
+		require.InDelta(tt, 1, limiter.tokens, 0.01)
+	})
+
+	t.Run("error - should stop waiting for the rate limit when the context is done", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		primary.EXPECT().SendMessageWithResponse(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		limiter := NewRateLimiter(1, 1)
+		require.NoError(tt, limiter.Wait(context.Background()))
+		d := &MessageDispatcher{
+			Providers: []Provider{{Name: "primary", Client: primary, Limiter: limiter}},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, failure := d.send(ctx, msg, body, "")
+		require.NotNil(tt, failure)
+		require.Equal(tt, failureRateLimited, failure.Kind)
+	})
+
+	t.Run("error - should fail if there is no provider", func(tt *testing.T) {
+		d := &MessageDispatcher{}
+		_, _, failure := d.send(context.Background(), msg, body, "")
+		require.NotNil(tt, failure)
+		require.False(tt, failure.Permanent)
+	})
+
+	t.Run("it should skip a provider that keeps failing until the cooldown is over", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		secondary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		d := &MessageDispatcher{
+			Providers:        []Provider{{Name: "primary", Client: primary}, {Name: "secondary", Client: secondary}},
+			FailureThreshold: 2,
+			ProviderCooldown: time.Hour,
+		}
+
+		// the primary fails twice in a row and is taken down
+		primary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(unavailable, nil).Times(2)
+		secondary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(accepted, nil).Times(3)
+		for range 2 {
+			provider, _, failure := d.send(context.Background(), msg, body, "")
+			require.Nil(tt, failure)
+			require.Equal(tt, "secondary", provider.Name)
+		}
+
+		// the primary is skipped
+		provider, _, failure := d.send(context.Background(), msg, body, "")
+		require.Nil(tt, failure)
+		require.Equal(tt, "secondary", provider.Name)
+		require.Equal(tt, []string{"secondary", "primary"}, providerNames(d.providersInOrder(body.To)))
+
+		// after the cooldown the primary is tried first again
+		d.health.downUntil["primary"] = time.Now().Add(-time.Second)
+		primary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(accepted, nil)
+		provider, _, failure = d.send(context.Background(), msg, body, "")
+		require.Nil(tt, failure)
+		require.Equal(tt, "primary", provider.Name)
+	})
+
+	t.Run("it should still try a provider that is down if every other provider fails", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		secondary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		d := &MessageDispatcher{
+			Providers:        []Provider{{Name: "primary", Client: primary}, {Name: "secondary", Client: secondary}},
+			FailureThreshold: 1,
+			ProviderCooldown: time.Hour,
+		}
+		d.health.failed("primary", d.FailureThreshold, d.ProviderCooldown, time.Now())
+
+		secondary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(unavailable, nil)
+		primary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(accepted, nil)
+
+		provider, _, failure := d.send(context.Background(), msg, body, "")
+		require.Nil(tt, failure)
+		require.Equal(tt, "primary", provider.Name)
+	})
+
+	t.Run("error - should not fail over if the provider timed out", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		secondary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		primary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(nil, fmt.Errorf("request failed: %w", context.DeadlineExceeded))
+
+		d := &MessageDispatcher{
+			Providers: []Provider{{Name: "primary", Client: primary}, {Name: "secondary", Client: secondary}},
+		}
+		provider, _, failure := d.send(context.Background(), msg, body, "")
+		require.NotNil(tt, failure)
+		require.Equal(tt, failureTimeout, failure.Kind)
+		require.True(tt, failure.MaybeSent)
+		require.Equal(tt, "primary", provider.Name, "the message should be retried with the provider that timed out")
+	})
+
+	t.Run("success - should only retry a message with the provider it timed out with", func(tt *testing.T) {
+		primary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+		secondary := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		secondary.EXPECT().SendMessageWithResponse(gomock.Any(), body, gomock.Any()).Return(accepted, nil)
+
+		d := &MessageDispatcher{
+			Providers: []Provider{{Name: "primary", Client: primary}, {Name: "secondary", Client: secondary}},
+		}
+		retryWith := "secondary"
+		timedOut := msg
+		timedOut.Provider = &retryWith
+		provider, _, failure := d.send(context.Background(), timedOut, body, "")
+		require.Nil(tt, failure)
+		require.Equal(tt, "secondary", provider.Name)
+	})
+
+	t.Run("error - should classify a response with an unparseable body by its status", func(tt *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("<html>Bad Request</html>"))
+		}))
+		defer server.Close()
+
+		client, err := somethirdparty.NewClientWithResponses(server.URL, somethirdparty.WithHTTPClient(recordingDoer{server.Client()}))
+		require.NoError(tt, err)
+
+		d := &MessageDispatcher{Providers: []Provider{{Name: "primary", Client: client}}}
+		_, _, failure := d.send(context.Background(), msg, body, "")
+		require.NotNil(tt, failure)
+		require.Equal(tt, failureRejected, failure.Kind)
+		require.True(tt, failure.Permanent)
+	})
+}
+
+func providerNames(providers []Provider) []string {
+	names := make([]string, len(providers))
+	for i, provider := range providers {
+		names[i] = provider.Name
+	}
+	return names
+}
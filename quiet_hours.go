@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/taylankasap/message-sender/api"
+	"github.com/taylankasap/message-sender/phonenumber"
+)
+
+// QuietHours is the time of day messages are not sent at, in the local time of the recipient
+type QuietHours struct {
+	Start time.Duration // Time of day quiet hours start at, e.g. 21 hours for 21:00
+	End   time.Duration // Time of day quiet hours end at, before Start if they span midnight
+
+	// ExemptPriority is the priority messages need to be sent during quiet hours, e.g. one
+	// time passwords. Zero exempts no message.
+	ExemptPriority int
+}
+
+// endAt returns the time the quiet hours containing the given time end, or the zero time if
+// the time is outside of quiet hours. Start and End are wall clock times, so quiet hours are
+// a bit shorter or longer on the days daylight saving time starts or ends.
+func (q QuietHours) endAt(t time.Time) time.Time {
+	if q.Start == q.End {
+		return time.Time{}
+	}
+
+	hour, minute, second := t.Clock()
+	timeOfDay := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second
+
+	var days int
+	switch {
+	case q.Start < q.End && timeOfDay >= q.Start && timeOfDay < q.End:
+		days = 0
+	case q.Start > q.End && timeOfDay < q.End:
+		days = 0
+	case q.Start > q.End && timeOfDay >= q.Start:
+		days = 1
+	default:
+		return time.Time{}
+	}
+
+	year, month, day := t.Date()
+	return time.Date(year, month, day+days, int(q.End/time.Hour), int(q.End%time.Hour/time.Minute), 0, 0, t.Location())
+}
+
+// quietHoursEnd returns the time the quiet hours of the recipient end if the message would be
+// sent during them, or the zero time if it can be sent now. Recipients whose time zone is not
+// known get messages at any time.
+func (d *MessageDispatcher) quietHoursEnd(msg api.Message, recipient string, now time.Time) time.Time {
+	if d.QuietHours == nil || (d.QuietHours.ExemptPriority > 0 && msg.Priority >= d.QuietHours.ExemptPriority) {
+		return time.Time{}
+	}
+
+	location := recipientLocation(msg, recipient)
+	if location == nil {
+		return time.Time{}
+	}
+	return d.QuietHours.endAt(now.In(location))
+}
+
+// recipientLocation returns the time zone set on the message, or the time zone of the country
+// of the recipient if it is not set. It returns nil if neither is known.
+func recipientLocation(msg api.Message, recipient string) *time.Location {
+	name := phonenumber.TimeZone(recipient)
+	if msg.TimeZone != nil && *msg.TimeZone != "" {
+		name = *msg.TimeZone
+	}
+	if name == "" {
+		return nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("failed to load time zone of message (id=%d): %v", msg.Id, err)
+		return nil
+	}
+	return location
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/taylankasap/message-sender/api"
+
+	"github.com/stretchr/testify/require"
+	somethirdparty "github.com/taylankasap/message-sender/some_third_party"
+	"go.uber.org/mock/gomock"
+)
+
+func TestQuietHours_endAt(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	require.NoError(t, err)
+
+	t.Run("it should return the end of quiet hours spanning midnight", func(tt *testing.T) {
+		q := QuietHours{Start: 21 * time.Hour, End: 9 * time.Hour}
+
+		cases := map[time.Time]time.Time{
+			time.Date(2025, 5, 31, 21, 0, 0, 0, istanbul):  time.Date(2025, 6, 1, 9, 0, 0, 0, istanbul),
+			time.Date(2025, 5, 31, 23, 59, 0, 0, istanbul): time.Date(2025, 6, 1, 9, 0, 0, 0, istanbul),
+			time.Date(2025, 6, 1, 3, 0, 0, 0, istanbul):    time.Date(2025, 6, 1, 9, 0, 0, 0, istanbul),
+			time.Date(2025, 12, 31, 22, 0, 0, 0, istanbul): time.Date(2026, 1, 1, 9, 0, 0, 0, istanbul),
+			time.Date(2025, 6, 1, 9, 0, 0, 0, istanbul):    {},
+			time.Date(2025, 6, 1, 15, 0, 0, 0, istanbul):   {},
+			time.Date(2025, 6, 1, 20, 59, 59, 0, istanbul): {},
+		}
+		for now, expected := range cases {
+			require.True(tt, expected.Equal(q.endAt(now)), now.String())
+		}
+	})
+
+	t.Run("it should return the end of quiet hours within a day", func(tt *testing.T) {
+		q := QuietHours{Start: 12 * time.Hour, End: 13*time.Hour + 30*time.Minute}
+
+		require.True(tt, time.Date(2025, 6, 1, 13, 30, 0, 0, istanbul).Equal(q.endAt(time.Date(2025, 6, 1, 12, 15, 0, 0, istanbul))))
+		require.Zero(tt, q.endAt(time.Date(2025, 6, 1, 11, 59, 0, 0, istanbul)))
+		require.Zero(tt, q.endAt(time.Date(2025, 6, 1, 13, 30, 0, 0, istanbul)))
+		require.Zero(tt, q.endAt(time.Date(2025, 6, 1, 23, 0, 0, 0, istanbul)))
+	})
+
+	t.Run("it should never be quiet if start and end are the same", func(tt *testing.T) {
+		q := QuietHours{Start: 9 * time.Hour, End: 9 * time.Hour}
+		require.Zero(tt, q.endAt(time.Date(2025, 6, 1, 9, 0, 0, 0, istanbul)))
+	})
+}
+
+func TestMessageDispatcher_quietHoursEnd(t *testing.T) {
+	// 22:00 in Istanbul, 15:00 in New York
+	now := time.Date(2025, 5, 31, 19, 0, 0, 0, time.UTC)
+	d := &MessageDispatcher{QuietHours: &QuietHours{Start: 21 * time.Hour, End: 9 * time.Hour, ExemptPriority: 5}}
+
+	t.Run("it should use the time zone of the country code", func(tt *testing.T) {
+		endAt := d.quietHoursEnd(api.Message{Id: 1}, "+905551111111", now)
+		require.True(tt, time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC).Equal(endAt))
+	})
+
+	t.Run("it should prefer the time zone of the message", func(tt *testing.T) {
+		timeZone := "America/New_York"
+		require.Zero(tt, d.quietHoursEnd(api.Message{Id: 1, TimeZone: &timeZone}, "+905551111111", now))
+	})
+
+	t.Run("it should send messages to recipients with an unknown time zone", func(tt *testing.T) {
+		require.Zero(tt, d.quietHoursEnd(api.Message{Id: 1}, "+14181234567", now))
+	})
+
+	t.Run("it should send messages with the exempt priority", func(tt *testing.T) {
+		require.Zero(tt, d.quietHoursEnd(api.Message{Id: 1, Priority: 5}, "+905551111111", now))
+		require.NotZero(tt, d.quietHoursEnd(api.Message{Id: 1, Priority: 4}, "+905551111111", now))
+	})
+
+	t.Run("it should send messages at any time without quiet hours", func(tt *testing.T) {
+		require.Zero(tt, (&MessageDispatcher{}).quietHoursEnd(api.Message{Id: 1}, "+905551111111", now))
+	})
+}
+
+func TestMessageDispatcher_processUnsentMessages_QuietHours(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("it should postpone a message in the quiet hours of the recipient instead of sending it", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockClient := somethirdparty.NewMockClientWithResponsesInterface(ctrl)
+
+		now := time.Now().UTC()
+		timeZone := "UTC"
+		msg := api.Message{Id: 123, Content: "Hello", Recipient: "+905551111111", TimeZone: &timeZone}
+		mockDB.EXPECT().GetUnsentMessages(gomock.Any()).Return([]api.Message{msg}, nil)
+		mockDB.EXPECT().MarkMessageAsSending(msg.Id).Return(msg, claimedAt, true, nil)
+		mockDB.EXPECT().IsSuppressed(msg.Recipient).Return(false, nil)
+		mockDB.EXPECT().DeferMessage(msg.Id, claimedAt, gomock.Any()).DoAndReturn(func(id int, claimedAt time.Time, until time.Time) error {
+			require.True(tt, time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Equal(until))
+			return nil
+		})
+
+		d := &MessageDispatcher{
+			DB:         mockDB,
+			Providers:  []Provider{{Name: "primary", Client: mockClient}},
+			QuietHours: &QuietHours{Start: 0, End: 24 * time.Hour}, // quiet all day
+		}
+		d.processUnsentMessages(context.Background())
+		d.inFlight.Wait()
+	})
+}
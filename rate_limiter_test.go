@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_reserve(t *testing.T) {
+	t.Run("it should allow a burst and then the configured rate", func(tt *testing.T) {
+		l := NewRateLimiter(2, 3)
+		now := time.Date(2025, 5, 31, 10, 0, 0, 0, time.UTC)
+
+		for range 3 {
+			require.Zero(tt, l.reserve(now))
+		}
+
+		// the bucket is empty, so callers queue up half a second apart
+		require.Equal(tt, 500*time.Millisecond, l.reserve(now))
+		require.Equal(tt, time.Second, l.reserve(now))
+
+		// the bucket refills at 2 tokens per second, but never above the burst
+		now = now.Add(time.Minute)
+		for range 3 {
+			require.Zero(tt, l.reserve(now))
+		}
+		require.Equal(tt, 500*time.Millisecond, l.reserve(now))
+	})
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	t.Run("it should not block without a limit", func(tt *testing.T) {
+		var l *RateLimiter
+		require.NoError(tt, l.Wait(context.Background()))
+		require.NoError(tt, NewRateLimiter(0, 0).Wait(context.Background()))
+	})
+
+	t.Run("it should wait for a token", func(tt *testing.T) {
+		l := NewRateLimiter(100, 1)
+
+		start := time.Now()
+		for range 3 {
+			require.NoError(tt, l.Wait(context.Background()))
+		}
+		require.GreaterOrEqual(tt, time.Since(start), 15*time.Millisecond)
+	})
+
+	t.Run("it should give the token back if the context is done", func(tt *testing.T) {
+		l := NewRateLimiter(1, 1)
+		require.NoError(tt, l.Wait(context.Background()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(tt, l.Wait(ctx), context.Canceled)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		require.InDelta(tt, 0, l.tokens, 0.01)
+	})
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/taylankasap/message-sender/api"
+)
+
+// routingTable picks the providers of a recipient by the longest route prefix it starts with
+type routingTable struct {
+	mu    sync.RWMutex
+	table api.RoutingTable
+}
+
+// lookup returns the names of the providers for the recipient, or nil if every provider can be used
+func (t *routingTable) lookup(recipient string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var match *api.Route
+	for i, route := range t.table.Routes {
+		if strings.HasPrefix(recipient, route.Prefix) && (match == nil || len(route.Prefix) > len(match.Prefix)) {
+			match = &t.table.Routes[i]
+		}
+	}
+	if match != nil {
+		return match.Providers
+	}
+	return t.table.DefaultProviders
+}
+
+// set replaces the routing table with a copy of the given one
+func (t *routingTable) set(table api.RoutingTable) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.table = cloneRoutingTable(table)
+}
+
+// Routes returns a copy of the routing table
+func (d *MessageDispatcher) Routes() api.RoutingTable {
+	d.routes.mu.RLock()
+	defer d.routes.mu.RUnlock()
+	return cloneRoutingTable(d.routes.table)
+}
+
+// SetRoutes stores and replaces the routing table, or returns api.ErrUnknownProvider if it refers
+// to a provider that is not configured
+func (d *MessageDispatcher) SetRoutes(table api.RoutingTable) error {
+	if err := d.checkRouteProviders(table); err != nil {
+		return err
+	}
+
+	if err := d.DB.SaveRoutingTable(table); err != nil {
+		return err
+	}
+	d.routes.set(table)
+	return nil
+}
+
+// LoadRoutes sets the routing table on start-up: the one stored by SetRoutes, or the configured
+// one if the routes were never replaced at runtime. It returns an error if the configured table
+// is invalid. A stored table that became invalid, e.g. because a provider was removed from the
+// config, is ignored.
+func (d *MessageDispatcher) LoadRoutes(configured api.RoutingTable) error {
+	if err := d.validateRoutes(configured); err != nil {
+		return fmt.Errorf("invalid routing config: %w", err)
+	}
+
+	table := configured
+	stored, err := d.DB.GetRoutingTable()
+	switch {
+	case errors.Is(err, api.ErrNotFound):
+	case err != nil:
+		return fmt.Errorf("failed to load routes: %w", err)
+	default:
+		if err := d.validateRoutes(stored); err != nil {
+			log.Printf("stored routes are invalid, using the configured ones instead: %v", err)
+		} else {
+			table = stored
+		}
+	}
+
+	d.routes.set(table)
+	return nil
+}
+
+// validateRoutes checks that every route can be matched against a recipient and only refers to
+// configured providers
+func (d *MessageDispatcher) validateRoutes(table api.RoutingTable) error {
+	if err := api.ValidateRoutingTable(table); err != nil {
+		return err
+	}
+	return d.checkRouteProviders(table)
+}
+
+// checkRouteProviders returns api.ErrUnknownProvider if the table refers to a provider that is
+// not configured
+func (d *MessageDispatcher) checkRouteProviders(table api.RoutingTable) error {
+	known := map[string]bool{}
+	for _, provider := range d.Providers {
+		known[provider.Name] = true
+	}
+
+	names := slices.Clone(table.DefaultProviders)
+	for _, route := range table.Routes {
+		names = append(names, route.Providers...)
+	}
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("%w: %s", api.ErrUnknownProvider, name)
+		}
+	}
+	return nil
+}
+
+// routeProviders returns the providers to send a message to the recipient with, in the order
+// they should be tried
+func (d *MessageDispatcher) routeProviders(recipient string) []Provider {
+	names := d.routes.lookup(recipient)
+	if len(names) == 0 {
+		return d.Providers
+	}
+
+	providers := make([]Provider, 0, len(names))
+	for _, name := range names {
+		for _, provider := range d.Providers {
+			if provider.Name == name {
+				providers = append(providers, provider)
+			}
+		}
+	}
+	return providers
+}
+
+// cloneRoutingTable returns a deep copy of the table, with empty lists instead of nil ones
+func cloneRoutingTable(table api.RoutingTable) api.RoutingTable {
+	clone := api.RoutingTable{
+		DefaultProviders: append([]string{}, table.DefaultProviders...),
+		Routes:           make([]api.Route, len(table.Routes)),
+	}
+	for i, route := range table.Routes {
+		clone.Routes[i] = api.Route{Prefix: route.Prefix, Providers: append([]string{}, route.Providers...)}
+	}
+	return clone
+}
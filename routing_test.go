@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/taylankasap/message-sender/api"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMessageDispatcher_routeProviders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockDBInterface(ctrl)
+	mockDB.EXPECT().SaveRoutingTable(gomock.Any()).Return(nil).Times(2)
+
+	d := &MessageDispatcher{
+		DB:        mockDB,
+		Providers: []Provider{{Name: "global"}, {Name: "turkey"}, {Name: "korea"}},
+	}
+
+	t.Run("it should use every provider if there are no routes", func(tt *testing.T) {
+		require.Equal(tt, []string{"global", "turkey", "korea"}, providerNames(d.routeProviders("+905551111111")))
+	})
+
+	t.Run("it should use the longest matching route or the default providers", func(tt *testing.T) {
+		require.NoError(tt, d.SetRoutes(api.RoutingTable{
+			DefaultProviders: []string{"global"},
+			Routes: []api.Route{
+				{Prefix: "+90", Providers: []string{"turkey", "global"}},
+				{Prefix: "+82", Providers: []string{"korea"}},
+				{Prefix: "+8212", Providers: []string{"global", "korea"}},
+			},
+		}))
+
+		require.Equal(tt, []string{"turkey", "global"}, providerNames(d.routeProviders("+905551111111")))
+		require.Equal(tt, []string{"korea"}, providerNames(d.routeProviders("+821051876804")))
+		require.Equal(tt, []string{"global", "korea"}, providerNames(d.routeProviders("+821260542022")))
+		require.Equal(tt, []string{"global"}, providerNames(d.routeProviders("+14181234567")))
+	})
+
+	t.Run("it should reject routes to unknown providers and keep the current ones", func(tt *testing.T) {
+		current := d.Routes()
+
+		err := d.SetRoutes(api.RoutingTable{Routes: []api.Route{{Prefix: "+1", Providers: []string{"usa"}}}})
+		require.ErrorIs(tt, err, api.ErrUnknownProvider)
+		require.ErrorContains(tt, err, "usa")
+
+		err = d.SetRoutes(api.RoutingTable{DefaultProviders: []string{"usa"}})
+		require.ErrorIs(tt, err, api.ErrUnknownProvider)
+
+		require.Equal(tt, current, d.Routes())
+	})
+
+	t.Run("it should not share the routing table with the caller", func(tt *testing.T) {
+		table := api.RoutingTable{Routes: []api.Route{{Prefix: "+90", Providers: []string{"turkey"}}}}
+		require.NoError(tt, d.SetRoutes(table))
+
+		table.Routes[0].Providers[0] = "korea"
+		routes := d.Routes()
+		require.Equal(tt, []string{"turkey"}, routes.Routes[0].Providers)
+		require.Equal(tt, []string{}, routes.DefaultProviders)
+
+		routes.Routes[0].Prefix = "+1"
+		require.Equal(tt, []string{"turkey"}, providerNames(d.routeProviders("+905551111111")))
+	})
+}
+
+func TestMessageDispatcher_SetRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	providers := []Provider{{Name: "global"}, {Name: "turkey"}}
+	table := api.RoutingTable{DefaultProviders: []string{"global"}, Routes: []api.Route{{Prefix: "+90", Providers: []string{"turkey"}}}}
+
+	t.Run("success - should store the routes so they survive a restart", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().SaveRoutingTable(table).Return(nil)
+
+		d := &MessageDispatcher{DB: mockDB, Providers: providers}
+		require.NoError(tt, d.SetRoutes(table))
+		require.Equal(tt, table, d.Routes())
+	})
+
+	t.Run("error - should keep the current routes if they cannot be stored", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().SaveRoutingTable(table).Return(fmt.Errorf("dummy error"))
+
+		d := &MessageDispatcher{DB: mockDB, Providers: providers}
+		require.Error(tt, d.SetRoutes(table))
+		require.Empty(tt, d.Routes().Routes)
+	})
+}
+
+func TestMessageDispatcher_LoadRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	providers := []Provider{{Name: "global"}, {Name: "turkey"}}
+	configured := api.RoutingTable{DefaultProviders: []string{"global"}, Routes: []api.Route{{Prefix: "+90", Providers: []string{"turkey"}}}}
+	stored := api.RoutingTable{DefaultProviders: []string{"turkey"}, Routes: []api.Route{}}
+
+	t.Run("success - should use the configured routes if none were stored", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().GetRoutingTable().Return(api.RoutingTable{}, api.ErrNotFound)
+
+		d := &MessageDispatcher{DB: mockDB, Providers: providers}
+		require.NoError(tt, d.LoadRoutes(configured))
+		require.Equal(tt, configured, d.Routes())
+	})
+
+	t.Run("success - should use the stored routes over the configured ones", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().GetRoutingTable().Return(stored, nil)
+
+		d := &MessageDispatcher{DB: mockDB, Providers: providers}
+		require.NoError(tt, d.LoadRoutes(configured))
+		require.Equal(tt, stored, d.Routes())
+	})
+
+	t.Run("success - should ignore stored routes to providers that are not configured anymore", func(tt *testing.T) {
+		mockDB := NewMockDBInterface(ctrl)
+		mockDB.EXPECT().GetRoutingTable().Return(api.RoutingTable{DefaultProviders: []string{"removed"}}, nil)
+
+		d := &MessageDispatcher{DB: mockDB, Providers: providers}
+		require.NoError(tt, d.LoadRoutes(configured))
+		require.Equal(tt, configured, d.Routes())
+	})
+
+	t.Run("error - should reject invalid configured routes", func(tt *testing.T) {
+		d := &MessageDispatcher{DB: NewMockDBInterface(ctrl), Providers: providers}
+
+		err := d.LoadRoutes(api.RoutingTable{Routes: []api.Route{{Prefix: "90", Providers: []string{"turkey"}}}})
+		require.ErrorIs(tt, err, api.ErrInvalidRoutePrefix)
+
+		err = d.LoadRoutes(api.RoutingTable{DefaultProviders: []string{"usa"}})
+		require.ErrorIs(tt, err, api.ErrUnknownProvider)
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	somethirdparty "github.com/taylankasap/message-sender/some_third_party"
+)
+
+// failureKind tells why the third party did not accept a message
+type failureKind string
+
+const (
+	failureRateLimited   failureKind = "rate limited"
+	failureInvalidNumber failureKind = "invalid number"
+	failureRejected      failureKind = "rejected"
+	failureServerError   failureKind = "server error"
+	failureTimeout       failureKind = "network timeout"
+	failureNetwork       failureKind = "network error"
+	failureUnexpected    failureKind = "unexpected response"
+)
+
+// sendFailure is the classified outcome of a send attempt that did not succeed
+type sendFailure struct {
+	Kind      failureKind
+	Permanent bool   // Sending the same message again will not succeed
+	MaybeSent bool   // The third party may have accepted the message, e.g. because it timed out
+	Reason    string // Human readable reason, stored as the last error of the message
+}
+
+// classifySendResult returns nil if the message was accepted, otherwise the reason it was not.
+// Whenever the third party responded the status decides, even if its body could not be parsed,
+// and only a request without a response is a network error.
+func classifySendResult(resp *somethirdparty.SendMessageResponse, err error) *sendFailure {
+	if err == nil && resp != nil && resp.JSON202 != nil {
+		return nil
+	}
+
+	if resp == nil || resp.HTTPResponse == nil {
+		if err == nil {
+			return newSendFailure(failureUnexpected, false, "no response")
+		}
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			failure := newSendFailure(failureTimeout, false, err.Error())
+			failure.MaybeSent = true
+			return failure
+		}
+		failure := newSendFailure(failureNetwork, false, err.Error())
+		// the request never reached the third party if it could not connect, but a connection
+		// that broke after the request was written may have delivered it
+		var opErr *net.OpError
+		failure.MaybeSent = !errors.As(err, &opErr) || opErr.Op != "dial"
+		return failure
+	}
+
+	switch status := resp.StatusCode(); {
+	case status == http.StatusTooManyRequests:
+		return newSendFailure(failureRateLimited, false, errorDetails(resp, resp.JSON429))
+	case status == http.StatusBadRequest && resp.JSON400 != nil && resp.JSON400.Code == "invalid_number":
+		return newSendFailure(failureInvalidNumber, true, errorDetails(resp, resp.JSON400))
+	case status == http.StatusBadRequest || status == http.StatusRequestEntityTooLarge || status == http.StatusUnprocessableEntity:
+		return newSendFailure(failureRejected, true, errorDetails(resp, resp.JSON400))
+	case status >= http.StatusInternalServerError:
+		details := resp.JSON500
+		if status == http.StatusServiceUnavailable {
+			details = resp.JSON503
+		}
+		return newSendFailure(failureServerError, false, errorDetails(resp, details))
+	default:
+		return newSendFailure(failureUnexpected, false, resp.Status())
+	}
+}
+
+func newSendFailure(kind failureKind, permanent bool, details string) *sendFailure {
+	return &sendFailure{
+		Kind:      kind,
+		Permanent: permanent,
+		Reason:    fmt.Sprintf("%s: %s", kind, details),
+	}
+}
+
+// errorDetails describes the response status and the error body if there is one
+func errorDetails(resp *somethirdparty.SendMessageResponse, body *somethirdparty.Error) string {
+	if body == nil {
+		return resp.Status()
+	}
+	return fmt.Sprintf("%s (%s: %s)", resp.Status(), body.Code, body.Message)
+}
+
+type responseRecorderKey struct{}
+
+// responseRecorder keeps the last response of a request, because the generated client drops
+// the response when its body cannot be parsed
+type responseRecorder struct {
+	resp *http.Response
+}
+
+// withResponseRecorder returns a context whose requests made with a recordingDoer are recorded
+func withResponseRecorder(ctx context.Context) (context.Context, *responseRecorder) {
+	recorder := &responseRecorder{}
+	return context.WithValue(ctx, responseRecorderKey{}, recorder), recorder
+}
+
+// recordingDoer records the responses of requests whose context has a responseRecorder
+type recordingDoer struct {
+	somethirdparty.HttpRequestDoer
+}
+
+func (d recordingDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := d.HttpRequestDoer.Do(req)
+	if recorder, ok := req.Context().Value(responseRecorderKey{}).(*responseRecorder); ok && resp != nil {
+		recorder.resp = resp
+	}
+	return resp, err
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	somethirdparty "github.com/taylankasap/message-sender/some_third_party"
+)
+
+func TestClassifySendResult(t *testing.T) {
+	response := func(status int, body *somethirdparty.Error) *somethirdparty.SendMessageResponse {
+		resp := &somethirdparty.SendMessageResponse{
+			HTTPResponse: &http.Response{Status: fmt.Sprintf("%d %s", status, http.StatusText(status)), StatusCode: status},
+		}
+		switch status {
+		case http.StatusBadRequest:
+			resp.JSON400 = body
+		case http.StatusTooManyRequests:
+			resp.JSON429 = body
+		case http.StatusInternalServerError:
+			resp.JSON500 = body
+		case http.StatusServiceUnavailable:
+			resp.JSON503 = body
+		}
+		return resp
+	}
+
+	t.Run("it should return nil if the message was accepted", func(tt *testing.T) {
+		resp := &somethirdparty.SendMessageResponse{JSON202: &somethirdparty.APIResponse{MessageId: "dummy-message-id"}}
+		require.Nil(tt, classifySendResult(resp, nil))
+	})
+
+	t.Run("it should only tell that a message may have been sent if the request may have reached the third party", func(tt *testing.T) {
+		require.True(tt, classifySendResult(nil, fmt.Errorf("request failed: %w", context.DeadlineExceeded)).MaybeSent)
+		require.True(tt, classifySendResult(nil, &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}).MaybeSent)
+		require.False(tt, classifySendResult(nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")}).MaybeSent)
+		require.False(tt, classifySendResult(response(http.StatusServiceUnavailable, nil), nil).MaybeSent)
+	})
+
+	cases := []struct {
+		name      string
+		resp      *somethirdparty.SendMessageResponse
+		err       error
+		kind      failureKind
+		permanent bool
+	}{
+		{
+			name: "timeout",
+			err:  fmt.Errorf("request failed: %w", context.DeadlineExceeded),
+			kind: failureTimeout,
+		},
+		{
+			name: "network error",
+			err:  fmt.Errorf("connection refused"),
+			kind: failureNetwork,
+		},
+		{
+			name: "rate limited",
+			resp: response(http.StatusTooManyRequests, &somethirdparty.Error{Code: "rate_limited", Message: "slow down"}),
+			kind: failureRateLimited,
+		},
+		{
+			name:      "invalid number",
+			resp:      response(http.StatusBadRequest, &somethirdparty.Error{Code: "invalid_number", Message: "not a mobile number"}),
+			kind:      failureInvalidNumber,
+			permanent: true,
+		},
+		{
+			name:      "other bad request",
+			resp:      response(http.StatusBadRequest, nil),
+			kind:      failureRejected,
+			permanent: true,
+		},
+		{
+			name: "server error",
+			resp: response(http.StatusInternalServerError, &somethirdparty.Error{Code: "internal", Message: "oops"}),
+			kind: failureServerError,
+		},
+		{
+			name: "service unavailable",
+			resp: response(http.StatusServiceUnavailable, nil),
+			kind: failureServerError,
+		},
+		{
+			name:      "bad request with an unparseable body",
+			resp:      response(http.StatusBadRequest, nil),
+			err:       fmt.Errorf("invalid character '<' looking for beginning of value"),
+			kind:      failureRejected,
+			permanent: true,
+		},
+		{
+			name: "service unavailable with an unparseable body",
+			resp: response(http.StatusServiceUnavailable, nil),
+			err:  fmt.Errorf("invalid character '<' looking for beginning of value"),
+			kind: failureServerError,
+		},
+		{
+			name: "unexpected response",
+			resp: response(http.StatusUnauthorized, nil),
+			kind: failureUnexpected,
+		},
+	}
+	for _, c := range cases {
+		t.Run("it should classify "+c.name, func(tt *testing.T) {
+			failure := classifySendResult(c.resp, c.err)
+			require.NotNil(tt, failure)
+			require.Equal(tt, c.kind, failure.Kind)
+			require.Equal(tt, c.permanent, failure.Permanent)
+			require.NotEmpty(tt, failure.Reason)
+		})
+	}
+}
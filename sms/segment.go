@@ -0,0 +1,90 @@
+// Package sms measures messages the way they are sent over SMS
+package sms
+
+import "unicode/utf16"
+
+// Encoding is the character set a message is sent in
+type Encoding string
+
+const (
+	GSM7 Encoding = "GSM-7" // 7-bit default alphabet, used when every character is in it
+	UCS2 Encoding = "UCS-2" // 16-bit encoding, used for everything else, e.g. emojis and Turkish characters
+)
+
+// Number of characters a single SMS can hold. When a message is split into multiple parts,
+// every part loses some characters to the header that is used to put the message back together.
+const (
+	GSM7SingleLength = 160
+	GSM7PartLength   = 153
+	UCS2SingleLength = 70
+	UCS2PartLength   = 67
+)
+
+// gsm7Basic is the GSM 03.38 default alphabet, every character takes one septet
+var gsm7Basic = runeSet("@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà")
+
+// gsm7Extension is the GSM 03.38 extension table, every character takes two septets
+// because it is preceded by an escape character
+var gsm7Extension = runeSet("\f^{}\\[~]|€")
+
+func runeSet(chars string) map[rune]bool {
+	set := make(map[rune]bool)
+	for _, r := range chars {
+		set[r] = true
+	}
+	return set
+}
+
+// Segmentation describes how a message is sent
+type Segmentation struct {
+	Encoding Encoding
+	Length   int // Number of characters in the encoding, i.e. septets for GSM-7 and 16-bit code units for UCS-2
+	Segments int // Number of SMS parts the message is split into, at least one
+}
+
+// Segment detects the encoding of the content and calculates how many SMS parts it takes
+func Segment(content string) Segmentation {
+	encoding := GSM7
+	for _, r := range content {
+		if !gsm7Basic[r] && !gsm7Extension[r] {
+			encoding = UCS2
+			break
+		}
+	}
+
+	singleLength, partLength := GSM7SingleLength, GSM7PartLength
+	if encoding == UCS2 {
+		singleLength, partLength = UCS2SingleLength, UCS2PartLength
+	}
+
+	s := Segmentation{Encoding: encoding, Segments: 1}
+	part := 0
+	for _, r := range content {
+		size := charLength(encoding, r)
+		s.Length += size
+
+		// a character is never split between two parts
+		if part+size > partLength {
+			s.Segments++
+			part = 0
+		}
+		part += size
+	}
+
+	if s.Length <= singleLength {
+		s.Segments = 1
+	}
+	return s
+}
+
+// charLength returns the number of characters r takes in the encoding
+func charLength(encoding Encoding, r rune) int {
+	if encoding == UCS2 {
+		return utf16.RuneLen(r)
+	}
+	if gsm7Extension[r] {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,39 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected Segmentation
+	}{
+		{"empty message", "", Segmentation{Encoding: GSM7, Length: 0, Segments: 1}},
+		{"GSM-7 message", "Hello!", Segmentation{Encoding: GSM7, Length: 6, Segments: 1}},
+		{"GSM-7 accented characters", "Ça va? Très bien", Segmentation{Encoding: GSM7, Length: 16, Segments: 1}},
+		{"extension characters take two septets", "Price: 5€ [50%]", Segmentation{Encoding: GSM7, Length: 18, Segments: 1}},
+		{"full GSM-7 message", strings.Repeat("a", 160), Segmentation{Encoding: GSM7, Length: 160, Segments: 1}},
+		{"GSM-7 message over the limit", strings.Repeat("a", 161), Segmentation{Encoding: GSM7, Length: 161, Segments: 2}},
+		{"two full GSM-7 parts", strings.Repeat("a", 306), Segmentation{Encoding: GSM7, Length: 306, Segments: 2}},
+		{"three GSM-7 parts", strings.Repeat("a", 307), Segmentation{Encoding: GSM7, Length: 307, Segments: 3}},
+		{"extension character is not split between parts", strings.Repeat("a", 152) + "€" + strings.Repeat("a", 10), Segmentation{Encoding: GSM7, Length: 164, Segments: 2}},
+		{"extension characters over the limit", strings.Repeat("€", 81), Segmentation{Encoding: GSM7, Length: 162, Segments: 2}},
+		{"Turkish characters", "Merhaba dünya, nasılsın?", Segmentation{Encoding: UCS2, Length: 24, Segments: 1}},
+		{"emoji takes two code units", "Hello 👋", Segmentation{Encoding: UCS2, Length: 8, Segments: 1}},
+		{"full UCS-2 message", strings.Repeat("ş", 70), Segmentation{Encoding: UCS2, Length: 70, Segments: 1}},
+		{"UCS-2 message over the limit", strings.Repeat("ş", 71), Segmentation{Encoding: UCS2, Length: 71, Segments: 2}},
+		{"three UCS-2 parts", strings.Repeat("ş", 135), Segmentation{Encoding: UCS2, Length: 135, Segments: 3}},
+		{"emoji is not split between parts", strings.Repeat("ş", 66) + "👋" + strings.Repeat("ş", 10), Segmentation{Encoding: UCS2, Length: 78, Segments: 2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			require.Equal(tt, test.expected, Segment(test.content))
+		})
+	}
+}
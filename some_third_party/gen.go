@@ -14,18 +14,40 @@ import (
 	"strings"
 )
 
+// Defines values for MessageEncoding.
+const (
+	GSM7 MessageEncoding = "GSM-7"
+	UCS2 MessageEncoding = "UCS-2"
+)
+
 // APIResponse defines model for APIResponse.
 type APIResponse struct {
 	Message   string `json:"message"`
 	MessageId string `json:"messageId"`
 }
 
+// Error defines model for Error.
+type Error struct {
+	// Code Machine readable error code, e.g. invalid_number or rate_limited
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 // Message defines model for Message.
 type Message struct {
 	Content string `json:"content"`
-	To      string `json:"to"`
+
+	// Encoding Character set the content is sent in
+	Encoding MessageEncoding `json:"encoding,omitempty"`
+
+	// Segments Number of SMS parts the content is split into. Messages with more than one part are delivered as a concatenated SMS and billed per part.
+	Segments int    `json:"segments,omitempty"`
+	To       string `json:"to"`
 }
 
+// MessageEncoding Character set the content is sent in
+type MessageEncoding string
+
 // SendMessageJSONRequestBody defines body for SendMessage for application/json ContentType.
 type SendMessageJSONRequestBody = Message
 
@@ -225,6 +247,10 @@ type SendMessageResponse struct {
 	Body         []byte
 	HTTPResponse *http.Response
 	JSON202      *APIResponse
+	JSON400      *Error
+	JSON429      *Error
+	JSON500      *Error
+	JSON503      *Error
 }
 
 // Status returns HTTPResponse.Status
@@ -281,6 +307,34 @@ func ParseSendMessageResponse(rsp *http.Response) (*SendMessageResponse, error)
 		}
 		response.JSON202 = &dest
 
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 429:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON429 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
 	}
 
 	return response, nil